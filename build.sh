@@ -4,25 +4,15 @@ set -euo pipefail
 
 export CMD=ci-utility
 
-TMP_ENV_FILE=$(mktemp)
-go run ./cmd/ci-utility git suggest-build-env --command-prefix "export " > "$TMP_ENV_FILE"
-if [[ ! -s "$TMP_ENV_FILE" ]]; then
+# Values are POSIX shell quoted so they can be evaluated directly.
+BUILD_ENV=$(go run ./cmd/ci-utility git suggest-build-env --command-prefix "export ")
+if [[ -z "$BUILD_ENV" ]]; then
     echo "Failed to determine build environment"
-    rm -f "$TMP_ENV_FILE"
     exit 1
 fi
 
-# check that the environment file is valid
-if ! grep -qE '^export BUILD_TIME=".+"$' "$TMP_ENV_FILE"; then
-    echo "Invalid environment file format: $TMP_ENV_FILE"
-    cat -vet "$TMP_ENV_FILE"
-    #rm -f "$TMP_ENV_FILE"
-    exit 1
-fi
-
-source "$TMP_ENV_FILE"
-echo -e "Using build environment:\n$(cat "$TMP_ENV_FILE")\n"
-rm -f "$TMP_ENV_FILE"
+eval "$BUILD_ENV"
+echo -e "Using build environment:\n${BUILD_ENV}\n"
 
 go run ./cmd/ci-utility matrix run -v \
     -d GOOS=linux \
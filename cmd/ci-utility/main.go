@@ -11,6 +11,7 @@ import (
 	"github.com/davidjspooner/ci-utility/internal/golang"
 	"github.com/davidjspooner/ci-utility/internal/llm"
 	"github.com/davidjspooner/ci-utility/internal/matrix"
+	"github.com/davidjspooner/ci-utility/internal/semver"
 	"github.com/davidjspooner/ci-utility/internal/template"
 	"github.com/davidjspooner/go-text-cli/pkg/cmd"
 )
@@ -46,6 +47,7 @@ func main() {
 	golang.AddCommandsTo(cmd.Root)
 	template.AddCommandsTo(cmd.Root)
 	matrix.AddCommandsTo(cmd.Root)
+	semver.AddCommandsTo(cmd.Root)
 	llm.AddCommandsTo(cmd.Root)
 
 	cmd.Root.SubCommands().Add(cmd.VersionCommand())
@@ -0,0 +1,133 @@
+package git
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/davidjspooner/ci-utility/pkg/semantic"
+)
+
+// Kinds of branch, which decide the versions that may be released from a branch.
+const (
+	branchKindMain     = "main"     // normal releases
+	branchKindRelease  = "release"  // patch releases within one major.minor line
+	branchKindOther    = "other"    // pre-releases labelled with the branch name
+	branchKindDetached = "detached" // unknown branch, no restrictions
+)
+
+// mainBranches lists the branch names which get normal releases.
+var mainBranches = []string{"main", "master"}
+
+// releaseBranchFmt matches maintenance branches such as release/1.4, release/v1.4 or release/1.4.x.
+var releaseBranchFmt = regexp.MustCompile(`^release/v?(\d+)\.(\d+)(?:\.x)?$`)
+
+// branchPolicy describes which versions may be released from a branch.
+type branchPolicy struct {
+	Branch string
+	Kind   string
+	Major  int    // release line, for release branches
+	Minor  int    // release line, for release branches
+	Label  string // pre-release label, for other branches
+
+	ReleasesOnly bool // ignore pre-release versions, eg when looking for the previous release
+}
+
+// branchPolicyFor returns the version policy for a branch. If the branch is empty or "HEAD"
+// (a detached checkout) the branch name is taken from the CI system, if any.
+func branchPolicyFor(branch string) branchPolicy {
+	if branch == "" || branch == "HEAD" {
+		if ci, ok := currentCI(); ok && ci.Branch != "" {
+			branch = ci.Branch
+		}
+	}
+	switch {
+	case branch == "" || branch == "HEAD":
+		slog.Warn("HEAD is detached, no branch policy applied. Use --branch to choose one.")
+		return branchPolicy{Branch: "HEAD", Kind: branchKindDetached}
+	case isMainBranch(branch):
+		return branchPolicy{Branch: branch, Kind: branchKindMain}
+	}
+	if m := releaseBranchFmt.FindStringSubmatch(branch); m != nil {
+		major, _ := strconv.Atoi(m[1])
+		minor, _ := strconv.Atoi(m[2])
+		return branchPolicy{Branch: branch, Kind: branchKindRelease, Major: major, Minor: minor}
+	}
+	return branchPolicy{Branch: branch, Kind: branchKindOther, Label: sanitizePreReleaseLabel(branch)}
+}
+
+// isMainBranch reports whether the branch gets normal releases.
+func isMainBranch(branch string) bool {
+	for _, name := range mainBranches {
+		if branch == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizePreReleaseLabel turns a branch name into a single SemVer pre-release identifier,
+// eg "feature/Add_Login" gives "feature-add-login".
+func sanitizePreReleaseLabel(branch string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(branch) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case sb.Len() > 0 && !strings.HasSuffix(sb.String(), "-"):
+			sb.WriteRune('-')
+		}
+	}
+	label := strings.TrimSuffix(sb.String(), "-")
+	if label == "" {
+		return "branch"
+	}
+	// A purely numeric identifier is compared numerically and may not have leading zeros.
+	if strings.Trim(label, "0123456789") == "" {
+		return "branch-" + label
+	}
+	return label
+}
+
+// line returns the release line of a release branch, eg "1.4".
+func (p branchPolicy) line() string {
+	return fmt.Sprintf("%d.%d", p.Major, p.Minor)
+}
+
+// allowsVersion reports whether a tagged version belongs to the branch. Release branches only
+// see tags in their own line, so a later minor release on main is never taken as the base.
+func (p branchPolicy) allowsVersion(v semantic.Version) bool {
+	if p.ReleasesOnly && v.IsPreRelease() {
+		return false
+	}
+	if p.Kind != branchKindRelease {
+		return true
+	}
+	return v.Major == p.Major && v.Minor == p.Minor
+}
+
+// checkBump returns an error if the bump is not allowed on the branch.
+func (p branchPolicy) checkBump(bump string) error {
+	if p.Kind != branchKindRelease {
+		return nil
+	}
+	switch bump {
+	case "patch", semantic.BumpNone:
+		return nil
+	}
+	return fmt.Errorf("release branch %s only allows patch releases within %s, but the commits require a %s bump", p.Branch, p.line(), bump)
+}
+
+// preReleaseLabel returns the pre-release label to use on the branch, if any. Other branches
+// always get a pre-release labelled with the branch name, after the requested label if one is set.
+func (p branchPolicy) preReleaseLabel(requested string) string {
+	if p.Kind != branchKindOther {
+		return requested
+	}
+	if requested == "" {
+		return p.Label
+	}
+	return requested + "." + p.Label
+}
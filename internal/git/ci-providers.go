@@ -0,0 +1,232 @@
+package git
+
+import (
+	"os"
+	"strings"
+)
+
+// ciContext describes the CI run that a build is executing in.
+type ciContext struct {
+	Provider string // eg "github-actions" or "gitlab-ci"
+	RunURL   string // link to the pipeline, build or workflow run
+	JobID    string
+	Commit   string // commit being built, which for pull requests may be a merge commit
+	Actor    string // user that triggered the run
+	Trigger  string // event that triggered the run, eg "push" or "pull_request"
+	Branch   string // branch being built, for CI systems that check out a detached HEAD
+	Base     string // target branch of the pull or merge request being built, if any
+}
+
+// ciProvider detects a CI system from environment variables.
+type ciProvider struct {
+	Name   string
+	Detect func(getenv func(string) string) bool
+	Fill   func(getenv func(string) string) ciContext
+}
+
+// ciProviders lists the known CI systems in detection order. More specific providers come
+// before the generic CI=true fallback, and Woodpecker comes before Drone because older
+// Woodpecker versions also set the DRONE_* variables.
+var ciProviders = []ciProvider{
+	{
+		Name:   "github-actions",
+		Detect: func(getenv func(string) string) bool { return getenv("GITHUB_RUN_ID") != "" },
+		Fill: func(getenv func(string) string) ciContext {
+			url := ""
+			if getenv("GITHUB_SERVER_URL") != "" && getenv("GITHUB_REPOSITORY") != "" {
+				url = getenv("GITHUB_SERVER_URL") + "/" + getenv("GITHUB_REPOSITORY")
+			} else {
+				url = getGitUrl("origin")
+			}
+			branch := getenv("GITHUB_HEAD_REF")
+			if branch == "" && getenv("GITHUB_REF_TYPE") == "branch" {
+				branch = getenv("GITHUB_REF_NAME")
+			}
+			// GITHUB_JOB is the job's key in the workflow, eg "build", which is the same for every
+			// run, so the job is identified by the run and its attempt, eg "1234567890-2/build".
+			jobID := getenv("GITHUB_RUN_ID")
+			if attempt := getenv("GITHUB_RUN_ATTEMPT"); attempt != "" {
+				jobID += "-" + attempt
+			}
+			if job := getenv("GITHUB_JOB"); job != "" {
+				jobID += "/" + job
+			}
+			return ciContext{
+				RunURL:  url + "/actions/runs/" + getenv("GITHUB_RUN_ID"),
+				JobID:   jobID,
+				Commit:  getenv("GITHUB_SHA"),
+				Actor:   getenv("GITHUB_ACTOR"),
+				Trigger: getenv("GITHUB_EVENT_NAME"),
+				Branch:  branch,
+				Base:    getenv("GITHUB_BASE_REF"),
+			}
+		},
+	},
+	{
+		Name:   "gitlab-ci",
+		Detect: func(getenv func(string) string) bool { return getenv("GITLAB_CI") != "" },
+		Fill: func(getenv func(string) string) ciContext {
+			return ciContext{
+				RunURL:  firstNonEmpty(getenv("CI_PIPELINE_URL"), getenv("CI_JOB_URL")),
+				JobID:   getenv("CI_JOB_ID"),
+				Commit:  getenv("CI_COMMIT_SHA"),
+				Actor:   firstNonEmpty(getenv("GITLAB_USER_LOGIN"), getenv("GITLAB_USER_NAME")),
+				Trigger: getenv("CI_PIPELINE_SOURCE"),
+				Branch:  firstNonEmpty(getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"), getenv("CI_COMMIT_BRANCH")),
+				Base:    getenv("CI_MERGE_REQUEST_TARGET_BRANCH_NAME"),
+			}
+		},
+	},
+	{
+		Name:   "jenkins",
+		Detect: func(getenv func(string) string) bool { return getenv("JENKINS_URL") != "" },
+		Fill: func(getenv func(string) string) ciContext {
+			jobID := getenv("BUILD_TAG")
+			if jobID == "" && getenv("JOB_NAME") != "" {
+				jobID = getenv("JOB_NAME") + "#" + getenv("BUILD_NUMBER")
+			}
+			trigger := ""
+			if getenv("CHANGE_ID") != "" {
+				trigger = "pull_request"
+			}
+			return ciContext{
+				RunURL:  getenv("BUILD_URL"),
+				JobID:   jobID,
+				Commit:  getenv("GIT_COMMIT"),
+				Actor:   firstNonEmpty(getenv("BUILD_USER_ID"), getenv("CHANGE_AUTHOR")),
+				Trigger: trigger,
+				Branch:  firstNonEmpty(getenv("CHANGE_BRANCH"), getenv("BRANCH_NAME")),
+				Base:    getenv("CHANGE_TARGET"),
+			}
+		},
+	},
+	{
+		Name:   "buildkite",
+		Detect: func(getenv func(string) string) bool { return getenv("BUILDKITE") == "true" },
+		Fill: func(getenv func(string) string) ciContext {
+			return ciContext{
+				RunURL:  getenv("BUILDKITE_BUILD_URL"),
+				JobID:   getenv("BUILDKITE_JOB_ID"),
+				Commit:  getenv("BUILDKITE_COMMIT"),
+				Actor:   firstNonEmpty(getenv("BUILDKITE_BUILD_CREATOR_EMAIL"), getenv("BUILDKITE_BUILD_CREATOR")),
+				Trigger: getenv("BUILDKITE_SOURCE"),
+				Branch:  getenv("BUILDKITE_BRANCH"),
+				Base:    getenv("BUILDKITE_PULL_REQUEST_BASE_BRANCH"),
+			}
+		},
+	},
+	{
+		Name:   "circleci",
+		Detect: func(getenv func(string) string) bool { return getenv("CIRCLECI") == "true" },
+		Fill: func(getenv func(string) string) ciContext {
+			trigger := ""
+			if getenv("CIRCLE_PULL_REQUEST") != "" {
+				trigger = "pull_request"
+			}
+			return ciContext{
+				RunURL:  getenv("CIRCLE_BUILD_URL"),
+				JobID:   firstNonEmpty(getenv("CIRCLE_WORKFLOW_JOB_ID"), getenv("CIRCLE_BUILD_NUM")),
+				Commit:  getenv("CIRCLE_SHA1"),
+				Actor:   getenv("CIRCLE_USERNAME"),
+				Trigger: trigger,
+				Branch:  getenv("CIRCLE_BRANCH"),
+			}
+		},
+	},
+	{
+		Name:   "azure-pipelines",
+		Detect: func(getenv func(string) string) bool { return strings.EqualFold(getenv("TF_BUILD"), "true") },
+		Fill: func(getenv func(string) string) ciContext {
+			url := ""
+			if getenv("SYSTEM_COLLECTIONURI") != "" && getenv("BUILD_BUILDID") != "" {
+				url = strings.TrimSuffix(getenv("SYSTEM_COLLECTIONURI"), "/") + "/" + getenv("SYSTEM_TEAMPROJECT") + "/_build/results?buildId=" + getenv("BUILD_BUILDID")
+			}
+			branch := firstNonEmpty(getenv("SYSTEM_PULLREQUEST_SOURCEBRANCH"), getenv("BUILD_SOURCEBRANCH"))
+			if strings.HasPrefix(branch, "refs/heads/") {
+				branch = strings.TrimPrefix(branch, "refs/heads/")
+			} else {
+				branch = ""
+			}
+			return ciContext{
+				RunURL:  url,
+				JobID:   firstNonEmpty(getenv("SYSTEM_JOBID"), getenv("BUILD_BUILDID")),
+				Commit:  getenv("BUILD_SOURCEVERSION"),
+				Actor:   firstNonEmpty(getenv("BUILD_REQUESTEDFOREMAIL"), getenv("BUILD_REQUESTEDFOR")),
+				Trigger: getenv("BUILD_REASON"),
+				Branch:  branch,
+				Base:    strings.TrimPrefix(getenv("SYSTEM_PULLREQUEST_TARGETBRANCH"), "refs/heads/"),
+			}
+		},
+	},
+	{
+		Name:   "woodpecker",
+		Detect: func(getenv func(string) string) bool { return getenv("CI") == "woodpecker" },
+		Fill: func(getenv func(string) string) ciContext {
+			return ciContext{
+				RunURL:  firstNonEmpty(getenv("CI_PIPELINE_URL"), getenv("CI_BUILD_LINK")),
+				JobID:   firstNonEmpty(getenv("CI_PIPELINE_NUMBER"), getenv("CI_BUILD_NUMBER")),
+				Commit:  getenv("CI_COMMIT_SHA"),
+				Actor:   firstNonEmpty(getenv("CI_COMMIT_AUTHOR"), getenv("CI_PIPELINE_CREATOR")),
+				Trigger: firstNonEmpty(getenv("CI_PIPELINE_EVENT"), getenv("CI_BUILD_EVENT")),
+				Branch:  firstNonEmpty(getenv("CI_COMMIT_SOURCE_BRANCH"), getenv("CI_COMMIT_BRANCH")),
+				Base:    getenv("CI_COMMIT_TARGET_BRANCH"),
+			}
+		},
+	},
+	{
+		Name:   "drone",
+		Detect: func(getenv func(string) string) bool { return getenv("DRONE") == "true" },
+		Fill: func(getenv func(string) string) ciContext {
+			base := ""
+			if getenv("DRONE_BUILD_EVENT") == "pull_request" {
+				// DRONE_TARGET_BRANCH is also set to the pushed branch for push events.
+				base = getenv("DRONE_TARGET_BRANCH")
+			}
+			return ciContext{
+				RunURL:  getenv("DRONE_BUILD_LINK"),
+				JobID:   getenv("DRONE_BUILD_NUMBER"),
+				Commit:  getenv("DRONE_COMMIT_SHA"),
+				Actor:   firstNonEmpty(getenv("DRONE_COMMIT_AUTHOR"), getenv("DRONE_BUILD_TRIGGER")),
+				Trigger: getenv("DRONE_BUILD_EVENT"),
+				Branch:  firstNonEmpty(getenv("DRONE_SOURCE_BRANCH"), getenv("DRONE_BRANCH")),
+				Base:    base,
+			}
+		},
+	},
+	{
+		Name: "ci",
+		Detect: func(getenv func(string) string) bool {
+			ci := strings.ToLower(getenv("CI"))
+			return ci == "true" || ci == "1"
+		},
+		Fill: func(getenv func(string) string) ciContext { return ciContext{} },
+	},
+}
+
+// detectCI returns the context of the CI system the process is running in, if any.
+func detectCI(getenv func(string) string) (ciContext, bool) {
+	for _, provider := range ciProviders {
+		if !provider.Detect(getenv) {
+			continue
+		}
+		ci := provider.Fill(getenv)
+		ci.Provider = provider.Name
+		return ci, true
+	}
+	return ciContext{}, false
+}
+
+// currentCI returns the context of the CI system the process is running in, if any.
+func currentCI() (ciContext, bool) {
+	return detectCI(os.Getenv)
+}
+
+// firstNonEmpty returns the first of its arguments which is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,220 @@
+package git
+
+import (
+	"testing"
+)
+
+func TestDetectCI(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		want   ciContext
+		wantOK bool
+	}{
+		{
+			name: "github actions push",
+			env: map[string]string{
+				"GITHUB_RUN_ID": "1234567890", "GITHUB_RUN_ATTEMPT": "2", "GITHUB_JOB": "build",
+				"GITHUB_SERVER_URL": "https://github.com", "GITHUB_REPOSITORY": "org/repo",
+				"GITHUB_SHA": "a1b2c3", "GITHUB_ACTOR": "octocat", "GITHUB_EVENT_NAME": "push",
+				"GITHUB_REF_TYPE": "branch", "GITHUB_REF_NAME": "main",
+			},
+			want: ciContext{
+				Provider: "github-actions", RunURL: "https://github.com/org/repo/actions/runs/1234567890",
+				JobID: "1234567890-2/build", Commit: "a1b2c3", Actor: "octocat", Trigger: "push", Branch: "main",
+			},
+			wantOK: true,
+		},
+		{
+			name: "github actions pull request",
+			env: map[string]string{
+				"GITHUB_RUN_ID": "42", "GITHUB_SERVER_URL": "https://github.com", "GITHUB_REPOSITORY": "org/repo",
+				"GITHUB_SHA": "merge", "GITHUB_EVENT_NAME": "pull_request",
+				"GITHUB_REF_TYPE": "branch", "GITHUB_REF_NAME": "7/merge",
+				"GITHUB_HEAD_REF": "feature/login", "GITHUB_BASE_REF": "main",
+			},
+			want: ciContext{
+				Provider: "github-actions", RunURL: "https://github.com/org/repo/actions/runs/42",
+				JobID: "42", Commit: "merge", Trigger: "pull_request", Branch: "feature/login", Base: "main",
+			},
+			wantOK: true,
+		},
+		{
+			name: "github actions tag is detached",
+			env: map[string]string{
+				"GITHUB_RUN_ID": "43", "GITHUB_SERVER_URL": "https://github.com", "GITHUB_REPOSITORY": "org/repo",
+				"GITHUB_SHA": "a1b2c3", "GITHUB_EVENT_NAME": "push", "GITHUB_REF_TYPE": "tag", "GITHUB_REF_NAME": "v1.2.0",
+			},
+			want: ciContext{
+				Provider: "github-actions", RunURL: "https://github.com/org/repo/actions/runs/43",
+				JobID: "43", Commit: "a1b2c3", Trigger: "push",
+			},
+			wantOK: true,
+		},
+		{
+			name: "gitlab branch pipeline",
+			env: map[string]string{
+				"GITLAB_CI": "true", "CI": "true", "CI_PIPELINE_URL": "https://gitlab.com/org/repo/-/pipelines/9",
+				"CI_JOB_ID": "99", "CI_COMMIT_SHA": "a1b2c3", "GITLAB_USER_LOGIN": "dev",
+				"CI_PIPELINE_SOURCE": "push", "CI_COMMIT_BRANCH": "main",
+			},
+			want: ciContext{
+				Provider: "gitlab-ci", RunURL: "https://gitlab.com/org/repo/-/pipelines/9",
+				JobID: "99", Commit: "a1b2c3", Actor: "dev", Trigger: "push", Branch: "main",
+			},
+			wantOK: true,
+		},
+		{
+			name: "gitlab merge request pipeline",
+			env: map[string]string{
+				"GITLAB_CI": "true", "CI_JOB_URL": "https://gitlab.com/org/repo/-/jobs/99", "CI_JOB_ID": "99",
+				"CI_COMMIT_SHA": "a1b2c3", "GITLAB_USER_NAME": "Dev", "CI_PIPELINE_SOURCE": "merge_request_event",
+				"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME": "feature/login", "CI_MERGE_REQUEST_TARGET_BRANCH_NAME": "main",
+			},
+			want: ciContext{
+				Provider: "gitlab-ci", RunURL: "https://gitlab.com/org/repo/-/jobs/99", JobID: "99", Commit: "a1b2c3",
+				Actor: "Dev", Trigger: "merge_request_event", Branch: "feature/login", Base: "main",
+			},
+			wantOK: true,
+		},
+		{
+			name: "gitlab tag pipeline is detached",
+			env: map[string]string{
+				"GITLAB_CI": "true", "CI_JOB_ID": "100", "CI_COMMIT_SHA": "a1b2c3", "CI_COMMIT_TAG": "v1.2.0", "CI_PIPELINE_SOURCE": "push",
+			},
+			want:   ciContext{Provider: "gitlab-ci", JobID: "100", Commit: "a1b2c3", Trigger: "push"},
+			wantOK: true,
+		},
+		{
+			name: "jenkins branch",
+			env: map[string]string{
+				"JENKINS_URL": "https://ci.example.com/", "BUILD_URL": "https://ci.example.com/job/repo/job/main/5/",
+				"BUILD_TAG": "jenkins-repo-main-5", "GIT_COMMIT": "a1b2c3", "BRANCH_NAME": "main",
+			},
+			want: ciContext{
+				Provider: "jenkins", RunURL: "https://ci.example.com/job/repo/job/main/5/",
+				JobID: "jenkins-repo-main-5", Commit: "a1b2c3", Branch: "main",
+			},
+			wantOK: true,
+		},
+		{
+			name: "jenkins pull request",
+			env: map[string]string{
+				"JENKINS_URL": "https://ci.example.com/", "JOB_NAME": "repo/PR-7", "BUILD_NUMBER": "3", "GIT_COMMIT": "a1b2c3",
+				"CHANGE_ID": "7", "CHANGE_AUTHOR": "dev", "CHANGE_BRANCH": "feature/login", "CHANGE_TARGET": "main", "BRANCH_NAME": "PR-7",
+			},
+			want: ciContext{
+				Provider: "jenkins", JobID: "repo/PR-7#3", Commit: "a1b2c3", Actor: "dev",
+				Trigger: "pull_request", Branch: "feature/login", Base: "main",
+			},
+			wantOK: true,
+		},
+		{
+			name: "buildkite pull request",
+			env: map[string]string{
+				"BUILDKITE": "true", "CI": "true", "BUILDKITE_BUILD_URL": "https://buildkite.com/org/repo/builds/8",
+				"BUILDKITE_JOB_ID": "job-uuid", "BUILDKITE_COMMIT": "a1b2c3", "BUILDKITE_BUILD_CREATOR": "Dev",
+				"BUILDKITE_SOURCE": "webhook", "BUILDKITE_BRANCH": "feature/login", "BUILDKITE_PULL_REQUEST_BASE_BRANCH": "main",
+			},
+			want: ciContext{
+				Provider: "buildkite", RunURL: "https://buildkite.com/org/repo/builds/8", JobID: "job-uuid", Commit: "a1b2c3",
+				Actor: "Dev", Trigger: "webhook", Branch: "feature/login", Base: "main",
+			},
+			wantOK: true,
+		},
+		{
+			name: "circleci pull request",
+			env: map[string]string{
+				"CIRCLECI": "true", "CI": "true", "CIRCLE_BUILD_URL": "https://circleci.com/gh/org/repo/12",
+				"CIRCLE_WORKFLOW_JOB_ID": "job-uuid", "CIRCLE_BUILD_NUM": "12", "CIRCLE_SHA1": "a1b2c3", "CIRCLE_USERNAME": "dev",
+				"CIRCLE_PULL_REQUEST": "https://github.com/org/repo/pull/7", "CIRCLE_BRANCH": "feature/login",
+			},
+			want: ciContext{
+				Provider: "circleci", RunURL: "https://circleci.com/gh/org/repo/12", JobID: "job-uuid", Commit: "a1b2c3",
+				Actor: "dev", Trigger: "pull_request", Branch: "feature/login",
+			},
+			wantOK: true,
+		},
+		{
+			name: "azure pipelines pull request",
+			env: map[string]string{
+				"TF_BUILD": "True", "SYSTEM_COLLECTIONURI": "https://dev.azure.com/org/", "SYSTEM_TEAMPROJECT": "project",
+				"BUILD_BUILDID": "77", "SYSTEM_JOBID": "job-uuid", "BUILD_SOURCEVERSION": "a1b2c3", "BUILD_REQUESTEDFOR": "Dev",
+				"BUILD_REASON": "PullRequest", "BUILD_SOURCEBRANCH": "refs/pull/7/merge",
+				"SYSTEM_PULLREQUEST_SOURCEBRANCH": "refs/heads/feature/login", "SYSTEM_PULLREQUEST_TARGETBRANCH": "refs/heads/main",
+			},
+			want: ciContext{
+				Provider: "azure-pipelines", RunURL: "https://dev.azure.com/org/project/_build/results?buildId=77",
+				JobID: "job-uuid", Commit: "a1b2c3", Actor: "Dev", Trigger: "PullRequest", Branch: "feature/login", Base: "main",
+			},
+			wantOK: true,
+		},
+		{
+			name: "azure pipelines tag is detached",
+			env: map[string]string{
+				"TF_BUILD": "True", "BUILD_BUILDID": "78", "BUILD_SOURCEVERSION": "a1b2c3",
+				"BUILD_REASON": "IndividualCI", "BUILD_SOURCEBRANCH": "refs/tags/v1.2.0",
+			},
+			want:   ciContext{Provider: "azure-pipelines", JobID: "78", Commit: "a1b2c3", Trigger: "IndividualCI"},
+			wantOK: true,
+		},
+		{
+			name: "woodpecker before drone",
+			env: map[string]string{
+				"CI": "woodpecker", "DRONE": "true", "CI_PIPELINE_URL": "https://ci.example.com/repos/1/pipeline/4",
+				"CI_PIPELINE_NUMBER": "4", "CI_COMMIT_SHA": "a1b2c3", "CI_COMMIT_AUTHOR": "dev", "CI_PIPELINE_EVENT": "pull_request",
+				"CI_COMMIT_SOURCE_BRANCH": "feature/login", "CI_COMMIT_TARGET_BRANCH": "main", "CI_COMMIT_BRANCH": "main",
+			},
+			want: ciContext{
+				Provider: "woodpecker", RunURL: "https://ci.example.com/repos/1/pipeline/4", JobID: "4", Commit: "a1b2c3",
+				Actor: "dev", Trigger: "pull_request", Branch: "feature/login", Base: "main",
+			},
+			wantOK: true,
+		},
+		{
+			name: "drone push has no base",
+			env: map[string]string{
+				"DRONE": "true", "CI": "true", "DRONE_BUILD_LINK": "https://drone.example.com/org/repo/6", "DRONE_BUILD_NUMBER": "6",
+				"DRONE_COMMIT_SHA": "a1b2c3", "DRONE_COMMIT_AUTHOR": "dev", "DRONE_BUILD_EVENT": "push",
+				"DRONE_BRANCH": "main", "DRONE_TARGET_BRANCH": "main",
+			},
+			want: ciContext{
+				Provider: "drone", RunURL: "https://drone.example.com/org/repo/6", JobID: "6", Commit: "a1b2c3",
+				Actor: "dev", Trigger: "push", Branch: "main",
+			},
+			wantOK: true,
+		},
+		{
+			name: "drone pull request",
+			env: map[string]string{
+				"DRONE": "true", "DRONE_BUILD_NUMBER": "7", "DRONE_COMMIT_SHA": "a1b2c3", "DRONE_BUILD_EVENT": "pull_request",
+				"DRONE_SOURCE_BRANCH": "feature/login", "DRONE_BRANCH": "main", "DRONE_TARGET_BRANCH": "main",
+			},
+			want: ciContext{
+				Provider: "drone", JobID: "7", Commit: "a1b2c3", Trigger: "pull_request", Branch: "feature/login", Base: "main",
+			},
+			wantOK: true,
+		},
+		{
+			name:   "generic ci",
+			env:    map[string]string{"CI": "1"},
+			want:   ciContext{Provider: "ci"},
+			wantOK: true,
+		},
+		{
+			name: "not in ci",
+			env:  map[string]string{"CI": "false", "HOME": "/home/dev"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := detectCI(func(key string) string { return tt.env[key] })
+			if ok != tt.wantOK {
+				t.Fatalf("expected detected %v, got %v", tt.wantOK, ok)
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
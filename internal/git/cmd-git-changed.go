@@ -0,0 +1,108 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/davidjspooner/ci-utility/internal/golang"
+)
+
+// ChangedOptions holds options for the changed command.
+type ChangedOptions struct {
+	Remote string `flag:"--remote,Remote of the pull request target branch used as the default base"`
+	Format string `flag:"--format,Output format (text, files, dimension or json); text and dimension print nothing if no package is affected"`
+	Name   string `flag:"--name,Dimension name for the dimension format, eg PACKAGE=./pkg/a,./pkg/b"`
+}
+
+// changedResult is the JSON output of the changed command.
+type changedResult struct {
+	Base     string                   `json:"base"`
+	Files    []string                 `json:"files"`
+	Packages []golang.AffectedPackage `json:"packages"`
+}
+
+// executeChanged lists the Go packages affected by the files changed between a base ref and HEAD,
+// including the packages which import them, so that only those need to be built and tested.
+func executeChanged(ctx context.Context, option *ChangedOptions, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("expected a single base ref, eg origin/main")
+	}
+	repo := ExecRepository{}
+	base := changedBase(ctx, option.Remote, strings.Join(args, ""))
+
+	result, err := findChanged(repo, ".", base)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Changed packages", "base", base, "files", len(result.Files), "packages", len(result.Packages))
+	if len(result.Packages) == 0 {
+		slog.InfoContext(ctx, "No packages are affected, nothing needs to be built or tested", "base", base)
+	}
+	return writeChangedResult(result, option)
+}
+
+// changedBase returns the ref to compare HEAD with: the given ref, the target branch of the pull
+// request being built, or the previous commit.
+func changedBase(ctx context.Context, remote, base string) string {
+	if base != "" {
+		return base
+	}
+	if ci, ok := currentCI(); ok && ci.Base != "" {
+		return remote + "/" + ci.Base
+	}
+	slog.InfoContext(ctx, "No base ref given and not building a pull request, comparing with HEAD^")
+	return "HEAD^"
+}
+
+// findChanged lists the files changed since base and maps them to the affected packages in the
+// modules under root, which is the directory the file paths are relative to.
+func findChanged(repo Repository, root, base string) (changedResult, error) {
+	files, err := repo.ChangedFiles(base)
+	if err != nil {
+		if shallow, _ := repo.IsShallow(); shallow {
+			return changedResult{}, fmt.Errorf("%w: the repository is a shallow clone; fetch the base branch with enough history to find where HEAD diverged from it", err)
+		}
+		return changedResult{}, err
+	}
+	packages, err := golang.AffectedPackages(root, files)
+	if err != nil {
+		return changedResult{}, err
+	}
+	return changedResult{Base: base, Files: files, Packages: packages}, nil
+}
+
+// writeChangedResult writes the affected packages in the requested format.
+func writeChangedResult(result changedResult, option *ChangedOptions) error {
+	dirs := make([]string, 0, len(result.Packages))
+	for _, p := range result.Packages {
+		dirs = append(dirs, p.Dir)
+	}
+	switch option.Format {
+	case "", "text":
+		for _, dir := range dirs {
+			fmt.Println(dir)
+		}
+	case "files":
+		for _, file := range result.Files {
+			fmt.Println(file)
+		}
+	case "dimension":
+		// Usable as a matrix run dimension, eg -d "$(ci-utility git changed --format dimension)".
+		// Nothing is printed without packages, as "PACKAGE=" would run the matrix once with an
+		// empty value, eg go test "" which tests the current directory.
+		if len(dirs) > 0 {
+			fmt.Printf("%s=%s\n", option.Name, strings.Join(dirs, ","))
+		}
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	default:
+		return fmt.Errorf("unknown format %q, expected text, files, dimension or json", option.Format)
+	}
+	return nil
+}
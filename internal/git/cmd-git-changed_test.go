@@ -0,0 +1,76 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files with the given contents under dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindChanged(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.mod":          "module example.com/app\n",
+		"main.go":         "package main\n\nimport \"example.com/app/lib\"\n\nfunc main() { lib.Run() }\n",
+		"lib/lib.go":      "package lib\n\nfunc Run() {}\n",
+		"lib/lib_test.go": "package lib\n",
+		"other/other.go":  "package other\n",
+	})
+
+	r := newFakeRepository()
+	r.commit("chore: init", "go.mod", "main.go", "lib/lib.go", "other/other.go")
+	r.checkout("feature/lib")
+	r.commit("fix: lib", "lib/lib.go")
+	r.commit("test: lib", "lib/lib_test.go")
+
+	result, err := findChanged(r, root, "main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(result.Files, []string{"lib/lib.go", "lib/lib_test.go"}) {
+		t.Errorf("unexpected files %v", result.Files)
+	}
+	dirs := []string{}
+	for _, p := range result.Packages {
+		dirs = append(dirs, p.Dir)
+	}
+	if !slices.Equal(dirs, []string{".", "./lib"}) {
+		t.Errorf("expected . and ./lib to be affected, got %v", dirs)
+	}
+
+	// Nothing has changed on main itself.
+	r.checkout("main")
+	result, err = findChanged(r, root, "main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Files) != 0 || len(result.Packages) != 0 {
+		t.Errorf("expected no changes, got %+v", result)
+	}
+}
+
+func TestFindChangedShallowClone(t *testing.T) {
+	r := newFakeRepository()
+	r.commit("chore: init", "main.go")
+	r.shallow = true
+
+	_, err := findChanged(r, t.TempDir(), "origin/main")
+	if err == nil || !strings.Contains(err.Error(), "shallow clone") {
+		t.Fatalf("expected a shallow clone error, got %v", err)
+	}
+}
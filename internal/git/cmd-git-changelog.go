@@ -0,0 +1,114 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/template"
+
+	"github.com/davidjspooner/ci-utility/pkg/semantic"
+)
+
+// ChangelogOptions holds options for the changelog command.
+type ChangelogOptions struct {
+	From         string `flag:"--from,Start of the commit range, exclusive (defaults to the latest tag before --to)"`
+	To           string `flag:"--to,End of the commit range, inclusive"`
+	Prefix       string `flag:"--prefix,Text before the version in the tags --from defaults to (eg v)"`
+	Component    string `flag:"--component,Component path whose tags and commits are used (eg tools/linter)"`
+	Format       string `flag:"--format,Output format (markdown, json or text)"`
+	Template     string `flag:"--template,Go template file used to render the changelog instead of --format"`
+	IncludeOther bool   `flag:"--include-other,Include commits which do not follow the conventional commit format"`
+}
+
+// executeChangelog prints the commits in a range grouped by conventional commit type.
+func executeChangelog(ctx context.Context, option *ChangelogOptions, args []string) error {
+	paths, err := componentPaths(option.Component)
+	if err != nil {
+		return err
+	}
+	tagPrefix := option.Prefix
+	if len(paths) > 0 {
+		tagPrefix = paths[0] + "/" + option.Prefix
+	}
+	changelog, err := buildChangelog(ctx, ExecRepository{}, option.From, option.To, tagPrefix, paths, option.IncludeOther)
+	if err != nil {
+		return err
+	}
+
+	// Render with a custom template if one is provided.
+	if option.Template != "" {
+		content, err := os.ReadFile(option.Template)
+		if err != nil {
+			return fmt.Errorf("failed to read template %s: %w", option.Template, err)
+		}
+		tmpl, err := template.New("changelog").Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("failed to parse template %s: %w", option.Template, err)
+		}
+		return tmpl.Execute(os.Stdout, changelog)
+	}
+
+	// Otherwise render in the requested format.
+	switch option.Format {
+	case "markdown", "md":
+		fmt.Print(changelog.Markdown())
+	case "text":
+		fmt.Print(changelog.Text())
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(changelog); err != nil {
+			return fmt.Errorf("failed to encode json: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported --format: %q . Please use 'markdown', 'json' or 'text'", option.Format)
+	}
+	return nil
+}
+
+// buildChangelog collects the commits in from..to which change any of paths, or all commits if
+// there are no paths, and groups them into a changelog. If from is empty the highest tag before
+// to with exactly tagPrefix before its version is used, or all commits up to to if there is none.
+// Pre-releases are skipped when to is a release tag, so that the notes of a release cover every
+// change since the previous release rather than since its last release candidate.
+func buildChangelog(ctx context.Context, repo Repository, from, to, tagPrefix string, paths []string, includeOther bool) (semantic.Changelog, error) {
+	if to == "" {
+		to = "HEAD"
+	}
+	if from == "" {
+		policy := branchPolicy{}
+		if _, _, version, err := semantic.ExtractVersionFromTag(to); err == nil && !version.IsPreRelease() {
+			policy.ReleasesOnly = true
+		}
+		// Look for tags before "to" so that a tagged "to" is not compared with itself.
+		latestTag, _, err := getLatestTagAndVersionWithPrefix(ctx, repo, to+"^", tagPrefix, policy)
+		if err != nil {
+			slog.WarnContext(ctx, "No previous tag found, using all commits", "to", to, "error", err)
+		}
+		from = latestTag
+	}
+
+	revisionRange := to
+	if from != "" {
+		revisionRange = fmt.Sprintf("%s..%s", from, to)
+	}
+	commits, err := repo.Log(revisionRange, paths...)
+	if err != nil {
+		return semantic.Changelog{}, err
+	}
+	slog.DebugContext(ctx, "Changelog commits", "range", revisionRange, "paths", paths, "count", len(commits))
+
+	changelog := semantic.NewChangelog(commits, includeOther)
+	changelog.From = from
+	changelog.To = to
+	return changelog, nil
+}
+
+// BuildChangelog collects the commits in from..to of the repository in the current directory and
+// groups them into a changelog, as the changelog command does. If from is empty the highest tag
+// before to with exactly tagPrefix before its version is used, and to defaults to HEAD.
+func BuildChangelog(ctx context.Context, from, to, tagPrefix string, includeOther bool) (semantic.Changelog, error) {
+	return buildChangelog(ctx, ExecRepository{}, from, to, tagPrefix, nil, includeOther)
+}
@@ -0,0 +1,90 @@
+package git
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestBuildChangelog(t *testing.T) {
+	r := newFakeRepository()
+	r.commit("chore: init", "README.md")
+	r.tag("v1.0.0")
+	r.commit("feat: search", "search.go")
+	r.tag("v1.1.0-rc.1")
+	r.commit("fix: search crash", "search.go")
+	r.commit("feat: linter", "tools/linter/main.go")
+	r.tag("tools/linter/v2.0.0")
+	r.tag("v1.1.0-rc.2")
+	linterFix := r.commit("fix: linter crash", "tools/linter/main.go")
+	r.commit("docs: search", "docs/search.md")
+	r.tag("v1.1.0")
+
+	tests := []struct {
+		name      string
+		to        string
+		prefix    string
+		paths     []string
+		wantFrom  string
+		wantLines []string
+	}{
+		{
+			name:      "release skips its release candidates",
+			to:        "v1.1.0",
+			prefix:    "v",
+			wantFrom:  "v1.0.0",
+			wantLines: []string{"search", "search crash", "linter", "linter crash", "search"},
+		},
+		{
+			name:      "release candidate starts from the previous release candidate",
+			to:        "v1.1.0-rc.2",
+			prefix:    "v",
+			wantFrom:  "v1.1.0-rc.1",
+			wantLines: []string{"search crash", "linter"},
+		},
+		{
+			name:      "untagged end starts from the previous tag of any kind",
+			to:        linterFix,
+			prefix:    "v",
+			wantFrom:  "v1.1.0-rc.2",
+			wantLines: []string{"linter crash"},
+		},
+		{
+			name:      "component tags and commits",
+			to:        "HEAD",
+			prefix:    "tools/linter/v",
+			paths:     []string{"tools/linter"},
+			wantFrom:  "tools/linter/v2.0.0",
+			wantLines: []string{"linter crash"},
+		},
+		{
+			name:      "no previous tag uses all commits",
+			to:        "v1.0.0",
+			prefix:    "v",
+			wantFrom:  "",
+			wantLines: []string{"init"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changelog, err := buildChangelog(context.Background(), r, "", tt.to, tt.prefix, tt.paths, true)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if changelog.From != tt.wantFrom {
+				t.Errorf("expected changelog from %q, got %q", tt.wantFrom, changelog.From)
+			}
+			lines := []string{}
+			for _, section := range changelog.Sections {
+				for _, commit := range section.Commits {
+					lines = append(lines, commit.Description)
+				}
+			}
+			slices.Sort(lines)
+			slices.Sort(tt.wantLines)
+			if !slices.Equal(lines, tt.wantLines) {
+				t.Errorf("expected commits %q, got %q", tt.wantLines, lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HooksOptions holds options for the hooks install and uninstall commands.
+type HooksOptions struct {
+	Hooks   []string `flag:"--hook,Hook to install or uninstall, may be repeated (commit-msg or pre-push, defaults to both)"`
+	Command string   `flag:"--command,Shell command the hooks run to call ci-utility (defaults to this executable)"`
+}
+
+// hookMarker identifies hooks written by this command so they are never mistaken for user hooks.
+const hookMarker = "# Installed by ci-utility git hooks install."
+
+// chainedSuffix is appended to the name of an existing hook which is moved aside and run first.
+const chainedSuffix = ".chained"
+
+// hookScripts holds the script for each supported hook. %[1]s is the hook marker and %[2]s the
+// ci-utility command. Any existing hook moved aside during install runs first.
+var hookScripts = map[string]string{
+	"commit-msg": `#!/bin/sh
+%[1]s
+# Remove with: ci-utility git hooks uninstall
+hook_dir=$(dirname "$0")
+if [ -x "$hook_dir/commit-msg.chained" ]; then
+	"$hook_dir/commit-msg.chained" "$@" || exit $?
+fi
+exec %[2]s git lint-commits --message-file "$1"
+`,
+	"pre-push": `#!/bin/sh
+%[1]s
+# Remove with: ci-utility git hooks uninstall
+hook_dir=$(dirname "$0")
+refs=$(cat)
+if [ -x "$hook_dir/pre-push.chained" ]; then
+	printf '%%s\n' "$refs" | "$hook_dir/pre-push.chained" "$@" || exit $?
+fi
+head=$(git rev-parse HEAD)
+printf '%%s\n' "$refs" | while read -r local_ref local_sha remote_ref remote_sha; do
+	# Only check branches which are created or updated.
+	case "$local_ref" in refs/heads/*) ;; *) continue ;; esac
+	case "$local_sha" in *[!0]*) ;; *) continue ;; esac
+
+	range=""
+	case "$remote_sha" in *[!0]*)
+		git cat-file -e "$remote_sha^{commit}" 2>/dev/null && range="$remote_sha..$local_sha" ;;
+	esac
+	if [ -z "$range" ]; then
+		# New branch, or the remote is ahead: check the commits which are not on the remote yet.
+		first=$(git rev-list --reverse "$local_sha" --not --remotes="$1" | head -n 1)
+		[ -n "$first" ] || continue
+		if git rev-parse -q --verify "$first^" >/dev/null; then
+			range="$first^..$local_sha"
+		else
+			range="$local_sha"
+		fi
+	fi
+	%[2]s git lint-commits "$range" || exit 1
+
+	# Check the pending version bump is allowed on the branch.
+	if [ "$local_sha" = "$head" ] && git describe --tags --abbrev=0 >/dev/null 2>&1; then
+		%[2]s git update-tag --dry-run --branch "${local_ref#refs/heads/}" || exit 1
+	fi
+done || exit 1
+`,
+}
+
+// executeHooksInstall writes the hooks into the hooks directory, moving any existing hooks aside
+// so that they are chained rather than overwritten.
+func executeHooksInstall(ctx context.Context, option *HooksOptions, args []string) error {
+	hooksDir, names, err := option.resolve()
+	if err != nil {
+		return err
+	}
+	command, err := option.command()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+
+	for _, name := range names {
+		hookPath := filepath.Join(hooksDir, name)
+		installed, err := isInstalledHook(hookPath)
+		if err != nil {
+			return err
+		}
+
+		// Move an existing user hook aside so that it runs first.
+		if _, err := os.Stat(hookPath); err == nil && !installed {
+			chainedPath := hookPath + chainedSuffix
+			if _, err := os.Stat(chainedPath); err == nil {
+				return fmt.Errorf("cannot chain %s: %s already exists", hookPath, chainedPath)
+			}
+			if err := os.Rename(hookPath, chainedPath); err != nil {
+				return fmt.Errorf("failed to move existing hook aside: %w", err)
+			}
+			slog.InfoContext(ctx, "Chained existing hook", "hook", name, "path", chainedPath)
+		}
+
+		script := fmt.Sprintf(hookScripts[name], hookMarker, command)
+		if err := os.WriteFile(hookPath, []byte(script), 0755); err != nil {
+			return fmt.Errorf("failed to write hook %s: %w", hookPath, err)
+		}
+		// WriteFile keeps the mode of an existing file, so make sure the hook is executable.
+		if err := os.Chmod(hookPath, 0755); err != nil {
+			return fmt.Errorf("failed to make hook %s executable: %w", hookPath, err)
+		}
+		slog.InfoContext(ctx, "Installed hook", "hook", name, "path", hookPath)
+	}
+	return nil
+}
+
+// executeHooksUninstall removes the hooks written by install and restores any chained hooks.
+// Hooks which were not written by install are left alone.
+func executeHooksUninstall(ctx context.Context, option *HooksOptions, args []string) error {
+	hooksDir, names, err := option.resolve()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		hookPath := filepath.Join(hooksDir, name)
+		installed, err := isInstalledHook(hookPath)
+		if err != nil {
+			return err
+		}
+		if !installed {
+			if _, err := os.Stat(hookPath); err == nil {
+				slog.WarnContext(ctx, "Hook was not installed by ci-utility, leaving it", "hook", name, "path", hookPath)
+			}
+			continue
+		}
+		if err := os.Remove(hookPath); err != nil {
+			return fmt.Errorf("failed to remove hook %s: %w", hookPath, err)
+		}
+		slog.InfoContext(ctx, "Removed hook", "hook", name, "path", hookPath)
+
+		// Restore the hook which was chained during install.
+		chainedPath := hookPath + chainedSuffix
+		if _, err := os.Stat(chainedPath); err == nil {
+			if err := os.Rename(chainedPath, hookPath); err != nil {
+				return fmt.Errorf("failed to restore chained hook: %w", err)
+			}
+			slog.InfoContext(ctx, "Restored chained hook", "hook", name, "path", hookPath)
+		}
+	}
+	return nil
+}
+
+// resolve returns the hooks directory, which respects core.hooksPath, and the hooks to act on.
+func (option *HooksOptions) resolve() (string, []string, error) {
+	names := splitList(option.Hooks)
+	if len(names) == 0 {
+		names = []string{"commit-msg", "pre-push"}
+	}
+	for _, name := range names {
+		if _, ok := hookScripts[name]; !ok {
+			return "", nil, fmt.Errorf("unsupported --hook: %q . Please use 'commit-msg' or 'pre-push'", name)
+		}
+	}
+	hooksDir, err := Run("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to find the hooks directory: %v", err)
+	}
+	return hooksDir, names, nil
+}
+
+// command returns the shell command the hooks use to run ci-utility. The current executable is used
+// unless it was built by "go run" into a temporary directory, in which case ci-utility must be on the PATH.
+func (option *HooksOptions) command() (string, error) {
+	if option.Command != "" {
+		return option.Command, nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the ci-utility executable, use --command: %w", err)
+	}
+	if strings.HasPrefix(exe, os.TempDir()) {
+		return "ci-utility", nil
+	}
+	return shellQuote(exe), nil
+}
+
+// isInstalledHook returns true if the hook exists and was written by install.
+func isInstalledHook(hookPath string) (bool, error) {
+	data, err := os.ReadFile(hookPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read hook %s: %w", hookPath, err)
+	}
+	return strings.Contains(string(data), hookMarker), nil
+}
@@ -0,0 +1,220 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/davidjspooner/ci-utility/pkg/semantic"
+)
+
+// LintCommitsOptions holds options for the lint-commits command.
+type LintCommitsOptions struct {
+	Config       string   `flag:"--config,YAML file with lint rules (defaults to .ci-utility.yaml at the repository root if present)"`
+	Types        []string `flag:"--type,Allowed commit type, may be repeated or comma separated (overrides the config)"`
+	Scopes       []string `flag:"--scope,Allowed commit scope, may be repeated or comma separated (overrides the config)"`
+	RequireScope bool     `flag:"--require-scope,Require every commit to have a scope"`
+	MaxLength    int      `flag:"--max-length,Maximum length of the commit header (overrides the config)"`
+	Format       string   `flag:"--format,Output format (text, json or github)"`
+	IncludeMerge bool     `flag:"--include-merges,Also lint merge commits and commits created by git revert"`
+	MessageFile  string   `flag:"--message-file,Lint the commit message in this file instead of a range (eg from a commit-msg hook)"`
+}
+
+// lintResult is the JSON output of the lint-commits command.
+type lintResult struct {
+	Range      string                   `json:"range"`
+	Commits    int                      `json:"commits"`
+	Violations []semantic.LintViolation `json:"violations"`
+}
+
+// executeLintCommits checks the commit messages in a range against the conventional commit rules.
+// It returns an error, and so a non-zero exit code, if any message breaks the rules.
+func executeLintCommits(ctx context.Context, option *LintCommitsOptions, args []string) error {
+	repo := ExecRepository{}
+	rules, err := option.rules(repo)
+	if err != nil {
+		return err
+	}
+	if option.MessageFile != "" {
+		return lintMessageFile(ctx, repo, rules, option)
+	}
+
+	// Default to the commits since the latest tag.
+	if len(args) > 1 {
+		return fmt.Errorf("expected a single revision range, eg origin/main..HEAD")
+	}
+	revisionRange := strings.Join(args, "")
+	if revisionRange == "" {
+		latestTag, _, err := getLatestTagAndVersion(ctx, repo, "HEAD")
+		if err != nil {
+			return fmt.Errorf("no revision range given and %v", err)
+		}
+		revisionRange = latestTag + "..HEAD"
+	}
+
+	commits, err := repo.Log(revisionRange)
+	if err != nil {
+		return err
+	}
+
+	result := lintResult{Range: revisionRange, Violations: []semantic.LintViolation{}}
+	failed := map[string]bool{}
+	for _, commit := range commits {
+		if !option.IncludeMerge && isGeneratedCommit(commit) {
+			slog.DebugContext(ctx, "Skipping generated commit", "commit", commit.ShortHash(), "header", commit.Header)
+			continue
+		}
+		result.Commits++
+		violations := rules.Lint(commit)
+		if len(violations) > 0 {
+			failed[commit.Hash] = true
+		}
+		result.Violations = append(result.Violations, violations...)
+	}
+
+	if err := writeLintResult(result, option.Format); err != nil {
+		return err
+	}
+	if len(result.Violations) > 0 {
+		return fmt.Errorf("%d of %d commit(s) in %s have %d violation(s)", len(failed), result.Commits, revisionRange, len(result.Violations))
+	}
+	slog.InfoContext(ctx, "All commit messages are valid", "range", revisionRange, "commits", result.Commits)
+	return nil
+}
+
+// lintMessageFile checks a single commit message which has not been committed yet. Messages for
+// fixup!, squash! and amend! commits are allowed as they are meant to be squashed before pushing.
+func lintMessageFile(ctx context.Context, repo Repository, rules semantic.LintRules, option *LintCommitsOptions) error {
+	message, err := readCommitMessageFile(repo, option.MessageFile)
+	if err != nil {
+		return err
+	}
+	commit := semantic.ParseCommit(message)
+	if commit.Header == "" {
+		// git aborts the commit itself if the message is empty.
+		return nil
+	}
+	for _, prefix := range []string{"fixup! ", "squash! ", "amend! "} {
+		if strings.HasPrefix(commit.Header, prefix) {
+			return nil
+		}
+	}
+	if !option.IncludeMerge && isGeneratedCommit(commit) {
+		slog.DebugContext(ctx, "Skipping generated commit", "header", commit.Header)
+		return nil
+	}
+
+	result := lintResult{Range: option.MessageFile, Commits: 1, Violations: rules.Lint(commit)}
+	if err := writeLintResult(result, option.Format); err != nil {
+		return err
+	}
+	if len(result.Violations) > 0 {
+		return fmt.Errorf("commit message has %d violation(s)", len(result.Violations))
+	}
+	return nil
+}
+
+// readCommitMessageFile reads a commit message file and removes the parts git strips before
+// committing: comment lines and everything after the scissors line of "git commit --verbose".
+func readCommitMessageFile(repo Repository, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit message: %w", err)
+	}
+	commentChar, _ := repo.Config("core.commentChar")
+	if commentChar == "" || commentChar == "auto" {
+		commentChar = "#"
+	}
+	scissors := commentChar + " ------------------------ >8 ------------------------"
+
+	lines := []string{}
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if line == scissors {
+			break
+		}
+		if strings.HasPrefix(line, commentChar) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// rules returns the lint rules from the config file with any command line overrides applied.
+func (option *LintCommitsOptions) rules(repo Repository) (semantic.LintRules, error) {
+	rules, err := semantic.LoadLintRules(configFile(repo, option.Config))
+	if err != nil {
+		return semantic.LintRules{}, err
+	}
+	if types := splitList(option.Types); len(types) > 0 {
+		rules.Types = types
+	}
+	if scopes := splitList(option.Scopes); len(scopes) > 0 {
+		rules.Scopes = scopes
+	}
+	if option.RequireScope {
+		rules.RequireScope = true
+	}
+	if option.MaxLength != 0 {
+		rules.MaxHeaderLength = option.MaxLength
+	}
+	return rules, nil
+}
+
+// splitList splits repeated and comma separated flag values into a single list.
+func splitList(values []string) []string {
+	list := []string{}
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// isGeneratedCommit returns true for merge and revert commits whose messages were written by git.
+func isGeneratedCommit(commit semantic.Commit) bool {
+	return strings.HasPrefix(commit.Header, "Merge ") || strings.HasPrefix(commit.Header, "Revert \"")
+}
+
+// writeLintResult writes the violations in the requested format.
+func writeLintResult(result lintResult, format string) error {
+	switch format {
+	case "", "text":
+		for _, v := range result.Violations {
+			fmt.Println(v.String())
+		}
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("failed to encode json: %w", err)
+		}
+	case "github":
+		// Workflow commands are shown as annotations on the run and the pull request.
+		for _, v := range result.Violations {
+			title := fmt.Sprintf("Commit %s: %s", v.ShortHash(), v.Rule)
+			fmt.Printf("::error title=%s::%s\n", escapeGithubProperty(title), escapeGithubData(v.Message+"\n"+v.Header))
+		}
+	default:
+		return fmt.Errorf("unsupported --format: %q . Please use 'text', 'json' or 'github'", format)
+	}
+	return nil
+}
+
+// escapeGithubData escapes the message of a GitHub Actions workflow command.
+func escapeGithubData(s string) string {
+	r := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	return r.Replace(s)
+}
+
+// escapeGithubProperty escapes a property value of a GitHub Actions workflow command.
+func escapeGithubProperty(s string) string {
+	r := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+	return r.Replace(s)
+}
@@ -2,20 +2,31 @@ package git
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/davidjspooner/ci-utility/pkg/semantic"
 	"github.com/davidjspooner/go-text-cli/pkg/cmd"
 )
 
 // SuggestBuildEnvOptions holds options for the suggest-build-env command.
 type SuggestBuildEnvOptions struct {
 	CommandPrefix string `flag:"--command-prefix,Prefix for command output"`
-	VersionPrefix string `flag:"--version-prefix,Prefix string for version"`
-	VersionSuffix string `flag:"--version-suffix,Suffix string for version"`
+	VersionPrefix string `flag:"--version-prefix,Text before the version in the release tags (eg v); other tags, such as component tags, are ignored"`
+	VersionSuffix string `flag:"--version-suffix,Text appended to the version of untagged builds (eg -linux), as update-tag --suffix appends it to tags"`
+	Format        string `flag:"--format,Output format (shell, dotenv, json, makefile, github-env, github-output or ldflags)"`
+	Package       string `flag:"--ldflags-package,Go package path for the -X flags in ldflags format (eg github.com/org/repo/pkg/version)"`
+	Reproducible  bool   `flag:"--reproducible,Use the HEAD commit time and a hash of uncommitted changes instead of the clock"`
+	Branch        string `flag:"--branch,Branch name to apply the version policy for (defaults to the current branch)"`
+	Remote        string `flag:"--remote,Remote to compare and fetch tags from"`
+	FetchTags     bool   `flag:"--fetch-tags,Fetch tags from the remote first, deepening a shallow clone until a tag is reachable"`
 }
 
 // executeSuggestBuildEnv prints environment variables for the current build context.
@@ -32,78 +43,283 @@ func executeSuggestBuildEnv(ctx context.Context, options *SuggestBuildEnvOptions
 	}
 
 	// Get the current branch.
-	currentBranch, err := GetCurrentBranch()
+	repo := ExecRepository{}
+	currentBranch, err := repo.CurrentBranch()
 	if err != nil {
 		return fmt.Errorf("failed to get current branch: %v", err)
 	}
 
-	// Get the latest tag and version.
-	latestTag, currentVersion, err := getLatestTagAndVersion(ctx, currentBranch)
+	// Get the latest tag and version allowed by the branch policy.
+	shallow, err := prepareHistory(ctx, repo, options.Remote, options.FetchTags)
 	if err != nil {
-		return fmt.Errorf("failed to get the latest tag: %v", err)
+		return err
+	}
+	policy := branchPolicyFor(firstNonEmpty(options.Branch, currentBranch))
+	latestTag, currentVersion, err := getLatestTagAndVersionWithPrefix(ctx, repo, currentBranch, options.VersionPrefix, policy)
+	if err != nil {
+		return fmt.Errorf("failed to get the latest tag: %w", diagnoseHistory(repo, err, options.Remote, shallow))
+	}
+	if shallow {
+		if err := checkMissingNewerTags(ctx, repo, options.Remote, options.VersionPrefix, policy, currentVersion); err != nil {
+			slog.WarnContext(ctx, "The build version may be out of date", "error", err)
+		}
 	}
 
 	// Log the current tag and version.
 	slog.DebugContext(ctx, "Current", "tag", latestTag, "version", currentVersion.String())
 
-	prefix := options.CommandPrefix
+	buildTime, err := getBuildTime(options.Reproducible)
+	if err != nil {
+		return err
+	}
+	nowStr := buildTime.UTC().Format(time.RFC1123)
+	ci, _ := currentCI()
+	values := []keyValue{
+		{"BUILD_BRANCH", currentBranch},
+		{"BUILD_VERSION", suggestBuildName(repo, options.VersionPrefix, options.VersionSuffix, latestTag, currentVersion, policy, buildTime, options.Reproducible)},
+		{"BUILD_FROM", getGitUrl(options.Remote)},
+		{"BUILD_BY", getBuildContext()},
+		{"BUILD_TIME", nowStr},
+		{"BUILD_CI", ci.Provider},
+		{"BUILD_RUN_URL", ci.RunURL},
+		{"BUILD_JOB_ID", ci.JobID},
+		{"BUILD_COMMIT", ci.Commit},
+		{"BUILD_ACTOR", ci.Actor},
+		{"BUILD_TRIGGER", ci.Trigger},
+	}
 
-	now := time.Now().UTC()
-	nowStr := now.Format(time.RFC1123)
 	// Print environment variables for the build.
-	fmt.Printf("%sBUILD_BRANCH=%s\n", prefix, quoteIfNeeded(currentBranch))
-	fmt.Printf("%sBUILD_VERSION=%s\n", prefix, quoteIfNeeded(suggestBuildName()))
-	fmt.Printf("%sBUILD_FROM=%s\n", prefix, quoteIfNeeded(getGitUrl()))
-	fmt.Printf("%sBUILD_BY=%s\n", prefix, quoteIfNeeded(getBuildContext()))
-	fmt.Printf("%sBUILD_TIME=%s\n", prefix, quoteIfNeeded(nowStr))
+	return writeBuildEnv(values, options)
+}
+
+// writeBuildEnv writes the build environment variables in the requested format.
+func writeBuildEnv(values []keyValue, options *SuggestBuildEnvOptions) error {
+	switch options.Format {
+	case "", "shell":
+		for _, kv := range values {
+			fmt.Printf("%s%s=%s\n", options.CommandPrefix, kv.Key, shellQuote(kv.Value))
+		}
+	case "dotenv":
+		for _, kv := range values {
+			fmt.Printf("%s=%s\n", kv.Key, dotenvQuote(kv.Value))
+		}
+	case "json":
+		m := make(map[string]string, len(values))
+		for _, kv := range values {
+			m[kv.Key] = kv.Value
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(m); err != nil {
+			return fmt.Errorf("failed to encode json: %w", err)
+		}
+	case "makefile":
+		for _, kv := range values {
+			fmt.Printf("%s := %s\n", kv.Key, makefileEscape(kv.Value))
+		}
+	case "github-env":
+		return appendToGithubFile("GITHUB_ENV", values)
+	case "github-output":
+		return appendToGithubFile("GITHUB_OUTPUT", values)
+	case "ldflags":
+		flags, err := ldflagsFor(options.Package, values)
+		if err != nil {
+			return err
+		}
+		fmt.Println(flags)
+	default:
+		return fmt.Errorf("unsupported --format: %q . Please use 'shell', 'dotenv', 'json', 'makefile', 'github-env', 'github-output' or 'ldflags'", options.Format)
+	}
 	return nil
 }
 
-func quoteIfNeeded(s string) string {
-	if strings.ContainsAny(s, " \t\n") {
-		return fmt.Sprintf("%q", s)
+// dotenvQuote double quotes a value for a .env file if needed, escaping backslashes, quotes, dollars and newlines.
+func dotenvQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\r\n\"'`$\\#=") {
+		return s
+	}
+	r := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "$", "\\$", "`", "\\`", "\n", "\\n", "\r", "\\r")
+	return "\"" + r.Replace(s) + "\""
+}
+
+// makefileEscape escapes a value for use on the right hand side of a Makefile assignment.
+func makefileEscape(s string) string {
+	r := strings.NewReplacer("$", "$$", "#", "\\#", "\r", " ", "\n", " ")
+	return r.Replace(s)
+}
+
+// ldflagsFor returns a Go linker flags string setting each value as a string variable in the package.
+func ldflagsFor(pkg string, values []keyValue) (string, error) {
+	if pkg == "" {
+		return "", fmt.Errorf("--ldflags-package is required for ldflags format")
+	}
+	flags := make([]string, 0, len(values))
+	for _, kv := range values {
+		if strings.ContainsAny(kv.Value, "\r\n") {
+			return "", fmt.Errorf("value of %s cannot be used in ldflags: contains a newline", kv.Key)
+		}
+		// The go command splits -ldflags on spaces, honouring single or double quotes without escapes.
+		arg := fmt.Sprintf("%s.%s=%s", pkg, kv.Key, kv.Value)
+		switch {
+		case !strings.ContainsAny(arg, " \t'\""):
+			flags = append(flags, "-X "+arg)
+		case !strings.Contains(arg, "'"):
+			flags = append(flags, "-X '"+arg+"'")
+		case !strings.Contains(arg, "\""):
+			flags = append(flags, "-X \""+arg+"\"")
+		default:
+			return "", fmt.Errorf("value of %s cannot be used in ldflags: contains both quote characters", kv.Key)
+		}
+	}
+	return strings.Join(flags, " "), nil
+}
+
+// getBuildTime returns the time to record for the build. SOURCE_DATE_EPOCH is always honoured if set,
+// otherwise reproducible builds use the HEAD commit time and other builds use the current time.
+func getBuildTime(reproducible bool) (time.Time, error) {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %v", epoch, err)
+		}
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	if !reproducible {
+		return time.Now().UTC(), nil
+	}
+	out, err := Run("log", "-1", "--format=%ct", "HEAD")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get HEAD commit time: %v", err)
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid HEAD commit time %q: %v", out, err)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// hashWorkingTreeChanges returns a hash of the uncommitted changes, including the names and
+// contents of untracked files, so the same changes on the same commit always give the same hash.
+func hashWorkingTreeChanges() (string, error) {
+	h := sha256.New()
+	diff, err := Run("diff", "HEAD", "--binary")
+	if err != nil {
+		return "", err
+	}
+	h.Write([]byte(diff))
+
+	untracked, err := Run("ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return "", err
 	}
-	return s
+	for _, name := range strings.Split(untracked, "\x00") {
+		if name == "" {
+			continue
+		}
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return "", fmt.Errorf("failed to read untracked file %s: %w", name, err)
+		}
+		fmt.Fprintf(h, "\x00%s\x00%d\x00", name, len(content))
+		h.Write(content)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 // suggestBuildName returns a string representing the build version or identifier.
-// It checks for uncommitted changes, tags, or falls back to the commit hash.
-func suggestBuildName() string {
+// It checks for uncommitted changes, a version tag with the prefix on HEAD, or falls back to a describe
+// style version followed by suffix. Builds with uncommitted changes are named from the build time, so that
+// the name agrees with BUILD_TIME, or from a hash of the changes if the build is reproducible.
+func suggestBuildName(repo Repository, prefix, suffix, latestTag string, currentVersion semantic.Version, policy branchPolicy, buildTime time.Time, reproducible bool) string {
 	// Check for uncommitted changes.
 	out, err := Run("status", "--porcelain")
 	if err != nil {
 		return "UNKNOWN"
 	}
 	if strings.TrimSpace(out) != "" {
-		// If there are uncommitted changes, use a timestamp.
-		return "HEAD." + time.Now().Format("060102.1504")
+		if !reproducible {
+			// If there are uncommitted changes, use the build time.
+			return "HEAD." + buildTime.Format("060102.1504")
+		}
+		commitHash, err := Run("rev-parse", "--short", "HEAD")
+		if err != nil {
+			return "UNKNOWN"
+		}
+		changes, err := hashWorkingTreeChanges()
+		if err != nil {
+			return "UNKNOWN"
+		}
+		return "HEAD." + commitHash + "." + changes[:12]
+	}
+
+	// Check for a version tag with the prefix, ignoring other tags such as component tags.
+	tags, err := Run("tag", "--points-at", "HEAD")
+	if err == nil {
+		for _, tag := range strings.Split(strings.TrimSpace(tags), "\n") {
+			tag = strings.TrimSpace(tag)
+			tagPrefix, _, _, err := semantic.ExtractVersionFromTag(tag)
+			if err == nil && tagPrefix == prefix {
+				// If HEAD is tagged, return the tag.
+				return tag
+			}
+		}
 	}
 
-	// Check for a tag version.
-	tag, err := Run("tag", "--contains", "HEAD")
-	if err == nil && tag != "" {
-		tags := strings.Split(strings.TrimSpace(tag), "\n")
-		tag = strings.TrimSpace(tags[0]) // Take the first tag if multiple are returned.
-		// If HEAD is tagged, return the tag.
-		return tag
+	// Fallback to a version describing the distance from the latest tag.
+	label := "dev"
+	if policy.Kind == branchKindOther {
+		label = policy.Label
+	}
+	version, err := describeVersion(repo, latestTag, currentVersion, label)
+	if err != nil {
+		slog.Warn("Failed to describe version", "error", err)
+		commitHash, err := Run("rev-parse", "--short", "HEAD")
+		if err != nil {
+			return "UNKNOWN"
+		}
+		return "COMMIT." + commitHash
 	}
+	return version.String() + suffix
+}
 
-	// Fallback to short commit hash.
-	commitHash, err := Run("rev-parse", "--short", "HEAD")
+// describeVersion returns a SemVer version for an untagged commit, similar to git describe.
+// The version is the next patch after the latest tag with a "<label>.N" pre-release, where N is the
+// number of commits since the tag, and the short commit hash as build metadata, eg 1.4.1-dev.7+g1a2b3c4.
+// If the latest tag is a pre-release the distance is appended to it, eg 1.5.0-rc.1.dev.3+g1a2b3c4,
+// so the result still sorts after the tag.
+func describeVersion(repo Repository, latestTag string, currentVersion semantic.Version, label string) (semantic.Version, error) {
+	revisionRange := "HEAD"
+	if latestTag != "" {
+		revisionRange = latestTag + "..HEAD"
+	}
+	distance, err := repo.CountCommits(revisionRange)
 	if err != nil {
-		return "UNKNOWN"
+		return semantic.Version{}, err
+	}
+	commitHash, err := repo.ShortHash("HEAD")
+	if err != nil {
+		return semantic.Version{}, err
+	}
+
+	version := currentVersion
+	version.Build = "g" + commitHash
+	if currentVersion.IsPreRelease() {
+		version.PreRelease = fmt.Sprintf("%s.%s.%d", currentVersion.PreRelease, label, distance)
+	} else {
+		version.Patch++
+		version.PreRelease = fmt.Sprintf("%s.%d", label, distance)
 	}
-	return "COMMIT." + commitHash
+	return version, nil
 }
 
-// getBuildContext returns a string describing the build context, such as a CI run ID or user@hostname.
+// getBuildContext returns a string describing the build context, such as a CI run URL or user@hostname.
 func getBuildContext() string {
-	// Check for github actions.
-	githubRunID := os.Getenv("GITHUB_RUN_ID")
-	if githubRunID != "" {
-		url := getGitUrl()
-		url += "/actions/runs/" + githubRunID
-		return "github-actions " + url
+	// Check for a known CI system.
+	if ci, ok := currentCI(); ok {
+		if detail := firstNonEmpty(ci.RunURL, ci.JobID); detail != "" {
+			return ci.Provider + " " + detail
+		}
+		return ci.Provider
 	}
 
 	// Fallback to user@hostname.
@@ -118,21 +334,12 @@ func getBuildContext() string {
 	return user + "@" + hostname
 }
 
-func getGitUrl() string {
-	// Get the remote URL for the origin.
-	out, err := Run("config", "--get", "remote.origin.url")
+// getGitUrl returns a browsable URL for the remote, without any credentials, or "UNKNOWN".
+func getGitUrl(remote string) string {
+	remoteURL, err := GetRemoteURL(remote)
 	if err != nil {
+		slog.Debug("No remote URL", "remote", remote, "error", err)
 		return "UNKNOWN"
 	}
-	out = strings.TrimSpace(out)
-
-	// If the URL is an SSH URL, convert it to HTTPS.
-	if strings.HasPrefix(out, "git@") {
-		out = strings.ReplaceAll(out, ":", "/")
-		out = strings.ReplaceAll(out, "git@", "https://")
-	}
-
-	out = strings.TrimSuffix(out, ".git")
-
-	return out
+	return firstNonEmpty(remoteURL.BrowseURL(), remoteURL.String())
 }
@@ -2,8 +2,12 @@ package git
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"os"
+	"path"
+	"strconv"
 	"strings"
 
 	"github.com/davidjspooner/ci-utility/pkg/semantic"
@@ -15,12 +19,134 @@ type BumpGitTagOptions struct {
 	Suffix string `flag:"--suffix,Suffix string"`
 	DryRun bool   `flag:"--dry-run,Do not push the tag"`
 	Remote string `flag:"--remote,Remote to push the tag to"`
+
+	PreRelease string `flag:"--prerelease,Create a pre-release tag with this label (eg rc, beta, alpha)"`
+	Promote    bool   `flag:"--promote,Promote the latest pre-release tag on HEAD to a release"`
+	BumpRules  string `flag:"--bump-rules,YAML file with bump rules (defaults to .ci-utility.yaml at the repository root if present)"`
+	Component  string `flag:"--component,Path of a component (eg tools/linter) to tag separately as <component>/<prefix><version>"`
+	Output     string `flag:"--output,Report the result on stdout as json or env, or append it to $GITHUB_OUTPUT with github"`
+	Branch     string `flag:"--branch,Branch name to apply the version policy for (defaults to the current branch)"`
+	FetchTags  bool   `flag:"--fetch-tags,Fetch tags from the remote first, deepening a shallow clone until a tag is reachable"`
+
+	Annotate   bool   `flag:"--annotate,Create an annotated tag"`
+	Message    string `flag:"--message,Message for an annotated tag (defaults to the list of commits since the previous tag)"`
+	Sign       string `flag:"--sign,Sign the tag with gpg, ssh or x509 (implies --annotate)"`
+	SigningKey string `flag:"--signing-key,Key to sign the tag with (defaults to git config user.signingkey)"`
+}
+
+// signFormats maps --sign values to git gpg.format values.
+var signFormats = map[string]string{
+	"gpg":  "openpgp",
+	"ssh":  "ssh",
+	"x509": "x509",
+}
+
+// tagOptions returns how to create the tag, including annotation and signing options.
+func (option *BumpGitTagOptions) tagOptions(message string) (TagOptions, error) {
+	if option.Sign == "" && !option.Annotate {
+		return TagOptions{}, nil
+	}
+	if option.Message != "" {
+		message = option.Message
+	}
+	tagOptions := TagOptions{Annotate: true, Message: message}
+	if option.Sign == "" {
+		return tagOptions, nil
+	}
+	format, ok := signFormats[option.Sign]
+	if !ok {
+		return TagOptions{}, fmt.Errorf("unsupported --sign: %q . Please use 'gpg', 'ssh' or 'x509'", option.Sign)
+	}
+	tagOptions.SignFormat = format
+	tagOptions.SigningKey = option.SigningKey
+	return tagOptions, nil
+}
+
+// tagMessage returns the default message for an annotated tag: the tag name followed by the commits it contains.
+func tagMessage(newTag string, commits []semantic.Commit) string {
+	sb := strings.Builder{}
+	sb.WriteString(newTag)
+	sb.WriteString("\n")
+	if len(commits) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, commit := range commits {
+		fmt.Fprintf(&sb, "- %s (%s)\n", commit.Header, commit.ShortHash())
+	}
+	return sb.String()
+}
+
+// tagResult describes the outcome of updating the tag.
+type tagResult struct {
+	PreviousTag string `json:"previous_tag"`
+	NewTag      string `json:"new_tag"`
+	Bump        string `json:"bump"`
+	Commit      string `json:"commit"`
+	CommitHash  string `json:"commit_hash"`
+	Pushed      bool   `json:"pushed"`
+}
+
+// values returns the result as ordered key/value pairs with lower case keys.
+func (r *tagResult) values() []keyValue {
+	return []keyValue{
+		{"previous_tag", r.PreviousTag},
+		{"new_tag", r.NewTag},
+		{"bump", r.Bump},
+		{"commit", r.Commit},
+		{"commit_hash", r.CommitHash},
+		{"pushed", strconv.FormatBool(r.Pushed)},
+	}
+}
+
+// writeTagResult reports the result in the requested output format.
+func writeTagResult(ctx context.Context, format string, result *tagResult) error {
+	switch format {
+	case "":
+		return nil
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("failed to encode json: %w", err)
+		}
+	case "env":
+		for _, kv := range result.values() {
+			fmt.Printf("%s=%s\n", strings.ToUpper(kv.Key), shellQuote(kv.Value))
+		}
+	case "github":
+		if err := appendToGithubFile("GITHUB_OUTPUT", result.values()); err != nil {
+			return err
+		}
+		slog.DebugContext(ctx, "Wrote outputs to $GITHUB_OUTPUT", "new_tag", result.NewTag)
+	default:
+		return fmt.Errorf("unsupported --output: %q . Please use 'json', 'env' or 'github'", format)
+	}
+	return nil
+}
+
+// componentPaths returns the cleaned component path as a list for limiting git log, or nil if no component is set.
+func componentPaths(component string) ([]string, error) {
+	if component == "" {
+		return nil, nil
+	}
+	cleaned := path.Clean(strings.TrimPrefix(component, "./"))
+	if cleaned == "." || cleaned == ".." || path.IsAbs(cleaned) || strings.HasPrefix(cleaned, "../") {
+		return nil, fmt.Errorf("invalid component path: %q", component)
+	}
+	return []string{cleaned}, nil
 }
 
 // generateNewTag creates a new tag string based on the prefix, suffix, current version, and bump reason.
-func generateNewTag(prefix, suffix string, currentVersion semantic.Version, reason string) (string, error) {
+// If preRelease is set the new version is a pre-release with that label.
+func generateNewTag(prefix, suffix string, currentVersion semantic.Version, reason, preRelease string) (string, error) {
 	// Increment the version
-	newVersion, err := currentVersion.Increment(reason)
+	var newVersion semantic.Version
+	var err error
+	if preRelease != "" {
+		newVersion, err = currentVersion.IncrementPreRelease(reason, preRelease)
+	} else {
+		newVersion, err = currentVersion.Increment(reason)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to increment version: %v", err)
 	}
@@ -31,18 +157,23 @@ func generateNewTag(prefix, suffix string, currentVersion semantic.Version, reas
 }
 
 // applyNewTag creates and pushes the new tag, unless DryRun is set.
-func applyNewTag(ctx context.Context, newTag string, option *BumpGitTagOptions) error {
+// The message is used if the tag is annotated or signed and no --message was given.
+func applyNewTag(ctx context.Context, repo Repository, newTag, message string, option *BumpGitTagOptions) error {
+	tagOptions, err := option.tagOptions(message)
+	if err != nil {
+		return err
+	}
 	if option.DryRun {
-		slog.InfoContext(ctx, "--dry-run", "newTag", newTag)
+		slog.InfoContext(ctx, "--dry-run", "newTag", newTag, "annotate", option.Annotate || option.Sign != "", "sign", option.Sign)
 		return nil
 	}
 
 	// Create and push the new tag
-	if _, err := Run("tag", newTag); err != nil {
-		return fmt.Errorf("failed to create tag: %v", err)
+	if err := repo.CreateTag(newTag, tagOptions); err != nil {
+		return err
 	}
-	if _, err := Run("push", option.Remote, newTag); err != nil {
-		return fmt.Errorf("failed to push tag: %v", err)
+	if err := repo.PushTag(option.Remote, newTag); err != nil {
+		return err
 	}
 
 	// no need to Log tag creation and push since it is in output of git command above.
@@ -51,115 +182,205 @@ func applyNewTag(ctx context.Context, newTag string, option *BumpGitTagOptions)
 
 // executeBumpGitTag determines the next version and applies a new tag based on commit messages.
 func executeBumpGitTag(ctx context.Context, option *BumpGitTagOptions, args []string) error {
+	result, err := bumpGitTag(ctx, ExecRepository{}, option)
+	if err != nil {
+		return err
+	}
+	return writeTagResult(ctx, option.Output, result)
+}
+
+// bumpGitTag determines the next version, applies the new tag and returns what was done.
+func bumpGitTag(ctx context.Context, repo Repository, option *BumpGitTagOptions) (*tagResult, error) {
 
 	// Get the current branch
-	currentBranch, err := GetCurrentBranch()
+	currentBranch, err := repo.CurrentBranch()
 	if err != nil {
-		return fmt.Errorf("failed to get current branch: %v", err)
+		return nil, fmt.Errorf("failed to get current branch: %v", err)
 	}
 
-	// Get the latest tag
-	latestTag, currentVersion, err := getLatestTagAndVersion(ctx, currentBranch)
+	// Get the latest tag, limited to the component if one is set
+	paths, err := componentPaths(option.Component)
+	if err != nil {
+		return nil, err
+	}
+	// Root and component tags are told apart by the exact text before the version.
+	tagPrefix := option.Prefix
+	if len(paths) > 0 {
+		tagPrefix = paths[0] + "/" + option.Prefix
+	}
+	shallow, err := prepareHistory(ctx, repo, option.Remote, option.FetchTags)
+	if err != nil {
+		return nil, err
+	}
+	policy := branchPolicyFor(firstNonEmpty(option.Branch, currentBranch))
+	latestTag, currentVersion, err := getLatestTagAndVersionWithPrefix(ctx, repo, currentBranch, tagPrefix, policy)
 	if err != nil {
-		return fmt.Errorf("failed to get the latest tag: %v", err)
+		return nil, fmt.Errorf("failed to get the latest tag: %w", diagnoseHistory(repo, err, option.Remote, shallow))
+	}
+
+	// Check the remote has no newer tags which were not fetched, before a tag is pushed or
+	// when a shallow clone makes it likely.
+	if shallow || !option.DryRun {
+		if err := checkMissingNewerTags(ctx, repo, option.Remote, tagPrefix, policy, currentVersion); err != nil {
+			return nil, err
+		}
 	}
 
 	// Log the current tag and version.
-	slog.InfoContext(ctx, "Current", "tag", latestTag, "version", currentVersion.String())
+	slog.InfoContext(ctx, "Current", "tag", latestTag, "version", currentVersion.String(), "component", option.Component, "branch", policy.Branch, "policy", policy.Kind)
 
-	// Get commit messages since the latest tag
-	validCommitMessages, _, err := getCommitsSinceTag(latestTag)
+	// Get commits since the latest tag, limited to files in the component if one is set
+	commits, err := getCommitsSinceTag(repo, latestTag, paths...)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	validCommitMessages = []string{
-		"refactor: update dependencies",
+
+	result := &tagResult{PreviousTag: latestTag, Bump: semantic.BumpNone}
+	if option.Promote {
+		if policy.Kind == branchKindOther {
+			return nil, fmt.Errorf("pre-releases cannot be promoted on branch %s, promote from a main or release branch", policy.Branch)
+		}
+		return result, promotePreRelease(ctx, repo, result, tagPrefix, currentVersion, commits, option)
 	}
 
-	if len(validCommitMessages) == 0 {
-		fmt.Println("No changes detected, no version increment needed.")
-		return nil
+	if len(commits) == 0 {
+		slog.InfoContext(ctx, "No changes detected, no version increment needed.")
+		return result, nil
 	}
 
-	// Determine the version reason
-	bump, reason, err := semantic.Bumps.GetVersionBump(validCommitMessages)
+	// Load the bump rules
+	bumps, err := semantic.LoadBumps(configFile(repo, option.BumpRules))
 	if err != nil {
-		return fmt.Errorf("failed to determine version increment: %v", err)
+		return nil, err
+	}
+
+	// Determine the version reason
+	bump, reason := bumps.HighestBump(commits)
+	result.Commit = reason.Header
+	result.CommitHash = reason.Hash
+	if bump == semantic.BumpNone {
+		slog.InfoContext(ctx, "No releasable changes detected, no version increment needed.")
+		return result, nil
+	}
+	if err := policy.checkBump(bump); err != nil {
+		return nil, fmt.Errorf("%w (commit %s: %s)", err, reason.ShortHash(), reason.Header)
 	}
+	result.Bump = bump
 
 	// Log the bump and reason.
-	slog.DebugContext(ctx, "Version increment needed", "commit", reason, "bump", bump)
+	slog.DebugContext(ctx, "Version increment needed", "commit", reason.Header, "bump", bump)
 
-	// Generate the new tag
-	newTag, err := generateNewTag(option.Prefix, option.Suffix, currentVersion, bump)
+	// Generate the new tag, as a pre-release if the branch policy requires one
+	newTag, err := generateNewTag(tagPrefix, option.Suffix, currentVersion, bump, policy.preReleaseLabel(option.PreRelease))
 	if err != nil {
-		return err
+		return nil, err
 	}
+	result.NewTag = newTag
 
 	// Apply the new tag
-	return applyNewTag(ctx, newTag, option)
+	if err := applyNewTag(ctx, repo, newTag, tagMessage(newTag, commits), option); err != nil {
+		return nil, err
+	}
+	result.Pushed = !option.DryRun
+	return result, nil
 }
 
-// getCommitsSinceTag returns commit messages and raw commits since the given tag.
-func getCommitsSinceTag(latestTag string) ([]string, []string, error) {
-	commitMessages, err := Run("log", fmt.Sprintf("%s..HEAD", latestTag), "--pretty=format:%s")
-	validCommitMessages := []string{}
+// promotePreRelease tags HEAD with the release version of the latest pre-release tag, eg v1.3.0-rc.2 becomes v1.3.0.
+// The pre-release tag must point at HEAD so that exactly the approved commit is released.
+func promotePreRelease(ctx context.Context, repo Repository, result *tagResult, tagPrefix string, currentVersion semantic.Version, commits []semantic.Commit, option *BumpGitTagOptions) error {
+	latestTag := result.PreviousTag
+	if option.PreRelease != "" {
+		return fmt.Errorf("--promote cannot be used with --prerelease")
+	}
+	if !currentVersion.IsPreRelease() {
+		return fmt.Errorf("latest tag %s is not a pre-release, nothing to promote", latestTag)
+	}
+	if len(commits) > 0 {
+		return fmt.Errorf("HEAD is %d commit(s) ahead of %s, promote from the pre-release commit", len(commits), latestTag)
+	}
+	// Commits outside a component are not listed, so also check the tag is on HEAD itself.
+	atHead, err := repo.IsAncestor("HEAD", latestTag)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get commit messages: %v", err)
+		return err
 	}
-	// Split commit messages into lines.
-	commits := splitLines(commitMessages)
-	for _, commit := range commits {
-		commit = strings.TrimSpace(commit)
-		if commit != "" {
-			validCommitMessages = append(validCommitMessages, commit)
-		}
+	if !atHead {
+		return fmt.Errorf("HEAD is ahead of %s, promote from the pre-release commit", latestTag)
 	}
-	return validCommitMessages, commits, nil
+
+	newTag := fmt.Sprintf("%s%s%s", tagPrefix, currentVersion.Release().String(), option.Suffix)
+	slog.InfoContext(ctx, "Promoting pre-release", "from", latestTag, "to", newTag)
+	result.NewTag = newTag
+	result.Bump = "release"
+	message := fmt.Sprintf("%s\n\nPromoted from %s\n", newTag, latestTag)
+	if err := applyNewTag(ctx, repo, newTag, message, option); err != nil {
+		return err
+	}
+	result.Pushed = !option.DryRun
+	return nil
+}
+
+// getCommitsSinceTag returns the parsed commits since the given tag, newest first.
+// If paths are given only commits which touch files under those paths are returned.
+func getCommitsSinceTag(repo Repository, latestTag string, paths ...string) ([]semantic.Commit, error) {
+	return repo.Log(fmt.Sprintf("%s..HEAD", latestTag), paths...)
 }
 
-// getLatestTagAndVersion finds the latest tag and its semantic version for the given branch.
-func getLatestTagAndVersion(ctx context.Context, branch string) (string, semantic.Version, error) {
-	commits, err := Run("rev-list", "--tags", "--no-walk", "--abbrev=0", "--date-order", branch)
+// getLatestTagAndVersion finds the tag with the highest semantic version reachable from the given branch,
+// whatever the text before its version.
+func getLatestTagAndVersion(ctx context.Context, repo Repository, branch string) (string, semantic.Version, error) {
+	return findLatestVersionTag(ctx, repo, branch, "", false, branchPolicy{})
+}
+
+// getLatestTagAndVersionWithPrefix finds the tag with the highest semantic version reachable from the
+// given branch. Only tags whose text before the version is exactly prefix are considered, eg "tools/linter/v"
+// matches "tools/linter/v1.4.0" but not "v1.4.0" or "tools/linter/extra/v1.4.0", and "v" does not match
+// component tags. Only versions allowed by the branch policy are considered, eg tags in the line of a
+// release branch.
+func getLatestTagAndVersionWithPrefix(ctx context.Context, repo Repository, branch, prefix string, policy branchPolicy) (string, semantic.Version, error) {
+	return findLatestVersionTag(ctx, repo, branch, prefix, true, policy)
+}
+
+// findLatestVersionTag finds the tag with the highest semantic version reachable from the given branch,
+// which starts with prefix, or has exactly prefix before its version if exact is set. The highest version
+// is used rather than the most recently tagged commit, so that a patch of an older release line which is
+// merged back, eg v1.0.1 tagged after v1.1.0, does not become the base and cause 1.1.x to be released again.
+func findLatestVersionTag(ctx context.Context, repo Repository, branch, prefix string, exact bool, policy branchPolicy) (string, semantic.Version, error) {
+	pattern := ""
+	if prefix != "" {
+		pattern = prefix + "*"
+	}
+	tags, err := repo.Tags(branch, pattern)
 	if err != nil {
 		return "", semantic.Version{}, fmt.Errorf("failed to get latest tags: %v", err)
 	}
 	var bestVersion semantic.Version
 	var bestTag string
 
-	commitList := splitLines(commits)
-	for _, commit := range commitList {
-		if commit == "" {
+	for _, tag := range tags {
+		slog.DebugContext(ctx, "Tag found", "tag", tag, "branch", branch)
+		tagPrefix, _, version, err := semantic.ExtractVersionFromTag(tag)
+		if err != nil {
 			continue
 		}
-		commit = strings.TrimSpace(commit)
-		checkCmd, err := Run("merge-base", "--is-ancestor", commit, branch)
-		if err != nil {
+		if exact && tagPrefix != prefix {
 			continue
 		}
-		// Log ancestor check.
-		slog.DebugContext(ctx, "Checked commit is ancestor", "commit", commit, "branch", branch, "checkCmd", checkCmd)
-		tagsForCommit, err := Run("tag", "--contains", commit)
-		if err != nil {
+		if !policy.allowsVersion(version) {
 			continue
 		}
-		// Log tags for commit.
-		slog.DebugContext(ctx, "Tags for commit found", "commit", commit, "tags", tagsForCommit)
-
-		for _, tag := range splitLines(tagsForCommit) {
-			slog.DebugContext(ctx, "Tag found", "tag", tag)
-			_, _, version, err := semantic.ExtractVersionFromTag(tag)
-			if err != nil {
-				continue
-			}
-			if version.IsGreaterThan(bestVersion) {
-				bestVersion = version
-				bestTag = tag
-			}
+		if bestTag == "" || version.IsGreaterThan(bestVersion) {
+			bestVersion = version
+			bestTag = tag
+		}
+	}
+	if bestTag == "" {
+		if policy.Kind == branchKindRelease {
+			return "", semantic.Version{}, fmt.Errorf("no valid tags in release line %s found for branch %s", policy.line(), branch)
 		}
-		if bestVersion.IsNotEmpty() {
-			return bestTag, bestVersion, nil
+		if exact {
+			return "", semantic.Version{}, fmt.Errorf("no valid tags with prefix %q found for branch %s", prefix, branch)
 		}
+		return "", semantic.Version{}, fmt.Errorf("no valid tags found for branch %s", branch)
 	}
-	return "", semantic.Version{}, fmt.Errorf("no valid tags found for branch %s", branch)
+	return bestTag, bestVersion, nil
 }
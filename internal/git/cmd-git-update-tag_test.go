@@ -0,0 +1,397 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidjspooner/ci-utility/pkg/semantic"
+)
+
+// testBumpRules writes bump rules for the tests to a file and returns its path, so that the tests
+// do not depend on the config file of the repository they run in. The rules make ci commits a
+// none bump, before the built-in rules.
+func testBumpRules(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bump-rules.yaml")
+	if err := os.WriteFile(path, []byte("bumps:\n  - level: none\n    types: [ci]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// releasedRepository returns a repository with v1.0.0 and v1.1.0 released on main.
+func releasedRepository() *fakeRepository {
+	r := newFakeRepository()
+	r.commit("chore: init")
+	r.tag("v1.0.0")
+	r.commit("feat: first feature")
+	r.tag("v1.1.0")
+	return r
+}
+
+func TestGetLatestTagAndVersionWithPrefix(t *testing.T) {
+	r := newFakeRepository()
+	r.commit("chore: init", "README.md")
+	r.tag("v1.0.0")
+	r.tag("not-a-version")
+	r.commit("feat: linter", "tools/linter/main.go")
+	r.tag("tools/linter/v3.0.0")
+	r.commit("fix: bug")
+	r.tag("v1.9.0")
+	r.commit("fix: another bug")
+	r.tag("v1.10.0")
+
+	// Tags on other branches are ignored until they are merged.
+	r.checkout("experiment")
+	r.commit("feat!: rewrite")
+	r.tag("v9.0.0")
+	r.checkout("main")
+	r.checkout("feature/merged")
+	r.commit("fix: merged fix")
+	r.tag("v1.10.1-rc.1")
+	r.checkout("main")
+	r.merge("feature/merged")
+
+	tests := []struct {
+		name    string
+		prefix  string
+		policy  branchPolicy
+		wantTag string
+		wantErr string
+	}{
+		{name: "highest merged version, compared numerically", prefix: "v", wantTag: "v1.10.1-rc.1"},
+		{name: "component prefix", prefix: "tools/linter/v", wantTag: "tools/linter/v3.0.0"},
+		{name: "unknown prefix", prefix: "tools/other/v", wantErr: `no valid tags with prefix "tools/other/v"`},
+		{name: "empty prefix only matches bare versions", prefix: "", wantErr: `no valid tags with prefix ""`},
+		{name: "release line", prefix: "v", policy: branchPolicy{Kind: branchKindRelease, Major: 1, Minor: 9}, wantTag: "v1.9.0"},
+		{name: "release line without tags", prefix: "v", policy: branchPolicy{Kind: branchKindRelease, Major: 2, Minor: 0}, wantErr: "no valid tags in release line 2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, version, err := getLatestTagAndVersionWithPrefix(context.Background(), r, "main", tt.prefix, tt.policy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tag != tt.wantTag {
+				t.Errorf("expected tag %s, got %s (version %s)", tt.wantTag, tag, version)
+			}
+		})
+	}
+}
+
+func TestBumpGitTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(r *fakeRepository)
+		option   BumpGitTagOptions
+		wantTag  string
+		wantBump string
+		wantErr  string
+	}{
+		{
+			name:     "fix is a patch",
+			setup:    func(r *fakeRepository) { r.commit("fix: crash") },
+			wantTag:  "v1.1.1",
+			wantBump: "patch",
+		},
+		{
+			name: "feat is a minor",
+			setup: func(r *fakeRepository) {
+				r.commit("fix: crash")
+				r.commit("feat(api): endpoint")
+				r.commit("docs: readme")
+			},
+			wantTag:  "v1.2.0",
+			wantBump: "minor",
+		},
+		{
+			name:     "breaking marker is a major",
+			setup:    func(r *fakeRepository) { r.commit("feat!: new api") },
+			wantTag:  "v2.0.0",
+			wantBump: "major",
+		},
+		{
+			name:     "breaking footer is a major",
+			setup:    func(r *fakeRepository) { r.commit("fix: api\n\nBREAKING CHANGE: removed the old endpoint") },
+			wantTag:  "v2.0.0",
+			wantBump: "major",
+		},
+		{
+			name:     "non conventional commits default to a patch",
+			setup:    func(r *fakeRepository) { r.commit("update things") },
+			wantTag:  "v1.1.1",
+			wantBump: "patch",
+		},
+		{
+			name:     "no commits since the tag",
+			setup:    func(r *fakeRepository) {},
+			wantBump: "none",
+		},
+		{
+			name:     "bump rules from the config",
+			setup:    func(r *fakeRepository) { r.commit("ci: cache modules") },
+			wantBump: "none",
+		},
+		{
+			name:     "pre-release",
+			setup:    func(r *fakeRepository) { r.commit("feat: beta feature") },
+			option:   BumpGitTagOptions{PreRelease: "rc"},
+			wantTag:  "v1.2.0-rc.1",
+			wantBump: "minor",
+		},
+		{
+			name: "next pre-release",
+			setup: func(r *fakeRepository) {
+				r.commit("feat: beta feature")
+				r.tag("v1.2.0-rc.1")
+				r.commit("fix: beta bug")
+			},
+			option:   BumpGitTagOptions{PreRelease: "rc"},
+			wantTag:  "v1.2.0-rc.2",
+			wantBump: "patch",
+		},
+		{
+			name: "promote pre-release on HEAD",
+			setup: func(r *fakeRepository) {
+				r.commit("feat: beta feature")
+				r.tag("v1.2.0-rc.1")
+			},
+			option:   BumpGitTagOptions{Promote: true},
+			wantTag:  "v1.2.0",
+			wantBump: "release",
+		},
+		{
+			name: "promote pre-release behind HEAD",
+			setup: func(r *fakeRepository) {
+				r.commit("feat: beta feature")
+				r.tag("v1.2.0-rc.1")
+				r.commit("fix: late fix")
+			},
+			option:  BumpGitTagOptions{Promote: true},
+			wantErr: "HEAD is 1 commit(s) ahead of v1.2.0-rc.1",
+		},
+		{
+			name: "release branch patch",
+			setup: func(r *fakeRepository) {
+				r.detach("v1.0.0")
+				r.checkout("release/1.0")
+				r.commit("fix: backport")
+			},
+			wantTag:  "v1.0.1",
+			wantBump: "patch",
+		},
+		{
+			name: "release branch refuses a minor",
+			setup: func(r *fakeRepository) {
+				r.detach("v1.0.0")
+				r.checkout("release/1.0")
+				r.commit("feat: new feature")
+			},
+			wantErr: "release branch release/1.0 only allows patch releases within 1.0",
+		},
+		{
+			name: "feature branch pre-release",
+			setup: func(r *fakeRepository) {
+				r.checkout("feature/Login_Page")
+				r.commit("feat: login")
+			},
+			wantTag:  "v1.2.0-feature-login-page.1",
+			wantBump: "minor",
+		},
+		{
+			name: "branch override",
+			setup: func(r *fakeRepository) {
+				r.checkout("feature/Login_Page")
+				r.commit("feat: login")
+			},
+			option:   BumpGitTagOptions{Branch: "main"},
+			wantTag:  "v1.2.0",
+			wantBump: "minor",
+		},
+		{
+			name: "component only counts its own commits",
+			setup: func(r *fakeRepository) {
+				r.commit("feat: linter", "tools/linter/main.go")
+				r.tag("tools/linter/v0.1.0")
+				r.commit("feat!: root change", "main.go")
+				r.commit("fix: linter bug", "tools/linter/rules.go")
+			},
+			option:   BumpGitTagOptions{Component: "tools/linter"},
+			wantTag:  "tools/linter/v0.1.1",
+			wantBump: "patch",
+		},
+		{
+			name: "merged patch of an older line is not the base",
+			setup: func(r *fakeRepository) {
+				r.detach("v1.0.0")
+				r.checkout("release/1.0")
+				r.commit("fix: backport")
+				r.tag("v1.0.1")
+				r.checkout("main")
+				r.merge("release/1.0")
+				r.commit("fix: crash")
+			},
+			wantTag:  "v1.1.1",
+			wantBump: "patch",
+		},
+		{
+			name: "root ignores higher component tags",
+			setup: func(r *fakeRepository) {
+				r.commit("chore: linter", "tools/linter/main.go")
+				r.tag("tools/linter/v5.0.0")
+				r.commit("fix: root bug", "main.go")
+			},
+			wantTag:  "v1.1.1",
+			wantBump: "patch",
+		},
+	}
+	bumpRules := testBumpRules(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := releasedRepository()
+			tt.setup(r)
+			option := tt.option
+			option.Prefix = "v"
+			option.Remote = "origin"
+			option.DryRun = true
+			option.BumpRules = bumpRules
+
+			result, err := bumpGitTag(context.Background(), r, &option)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.NewTag != tt.wantTag || result.Bump != tt.wantBump {
+				t.Errorf("expected %q (%s), got %q (%s)", tt.wantTag, tt.wantBump, result.NewTag, result.Bump)
+			}
+			if len(r.created) != 0 || len(r.pushed) != 0 {
+				t.Errorf("dry run created %v and pushed %v", r.created, r.pushed)
+			}
+		})
+	}
+}
+
+func TestBumpGitTagCreatesAndPushes(t *testing.T) {
+	r := releasedRepository()
+	r.commit("fix: crash")
+	option := BumpGitTagOptions{Prefix: "v", Remote: "upstream", Annotate: true, BumpRules: testBumpRules(t)}
+
+	result, err := bumpGitTag(context.Background(), r, &option)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Pushed || result.PreviousTag != "v1.1.0" || result.NewTag != "v1.1.1" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if len(r.created) != 1 || r.created[0] != "v1.1.1" {
+		t.Fatalf("expected v1.1.1 to be created, got %v", r.created)
+	}
+	if !r.options[0].Annotate || !strings.Contains(r.options[0].Message, "- fix: crash") {
+		t.Errorf("expected an annotated tag listing the commits, got %+v", r.options[0])
+	}
+	if len(r.pushed) != 1 || r.pushed[0] != "upstream v1.1.1" {
+		t.Errorf("expected v1.1.1 to be pushed to upstream, got %v", r.pushed)
+	}
+}
+
+func TestBumpGitTagMissingTags(t *testing.T) {
+	// A newer release on the remote which was not fetched must not be released again.
+	r := releasedRepository()
+	r.commit("feat: second feature")
+	r.tag("v1.2.0")
+	delete(r.tags, "v1.2.0")
+	r.commit("fix: crash")
+
+	option := BumpGitTagOptions{Prefix: "v", Remote: "origin", BumpRules: testBumpRules(t)}
+	if _, err := bumpGitTag(context.Background(), r, &option); err == nil || !strings.Contains(err.Error(), "tag v1.2.0 on origin is newer than 1.1.0") {
+		t.Fatalf("expected a missing newer tag error, got %v", err)
+	}
+	if len(r.created) != 0 {
+		t.Fatalf("expected no tag to be created, got %v", r.created)
+	}
+
+	// Fetching the tags first finds the newer release.
+	option.FetchTags = true
+	result, err := bumpGitTag(context.Background(), r, &option)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(r.fetches) != 1 || result.NewTag != "v1.2.1" {
+		t.Errorf("expected tags to be fetched once and v1.2.1 to be created, got %v and %+v", r.fetches, result)
+	}
+}
+
+func TestBumpGitTagShallowClone(t *testing.T) {
+	r := releasedRepository()
+	r.commit("fix: crash")
+	r.shallow = true
+	r.tags = map[string]string{}
+
+	option := BumpGitTagOptions{Prefix: "v", Remote: "origin", DryRun: true, BumpRules: testBumpRules(t)}
+	_, err := bumpGitTag(context.Background(), r, &option)
+	if err == nil || !strings.Contains(err.Error(), "shallow clone") || !strings.Contains(err.Error(), "2 tag(s) on origin are missing locally") {
+		t.Fatalf("expected a shallow clone diagnostic, got %v", err)
+	}
+
+	// The fake has the whole history, so fetching the tags is enough to reach one.
+	option.FetchTags = true
+	result, err := bumpGitTag(context.Background(), r, &option)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.NewTag != "v1.1.1" || len(r.fetches) != 1 || r.fetches[0] != 0 {
+		t.Errorf("expected one fetch without deepening and v1.1.1, got %v and %+v", r.fetches, result)
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	root := t.TempDir()
+	config := "bumps:\n  - level: none\n    types: [chore]\n"
+	if err := os.WriteFile(filepath.Join(root, semantic.DefaultConfigFile), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		root string
+		path string
+		want string
+	}{
+		{name: "config at the root", root: root, want: filepath.Join(root, semantic.DefaultConfigFile)},
+		{name: "no config at the root", root: t.TempDir(), want: ""},
+		{name: "explicit path", root: root, path: "rules.yaml", want: "rules.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newFakeRepository()
+			r.root = tt.root
+			if got := configFile(r, tt.path); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	r := newFakeRepository()
+	r.root = root
+	bumps, err := semantic.LoadBumps(configFile(r, ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if level := bumps.LevelFor(semantic.ParseCommit("chore: deps")); level != semantic.BumpNone {
+		t.Errorf("expected the rules at the repository root to make chore a none bump, got %s", level)
+	}
+}
@@ -0,0 +1,129 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/davidjspooner/ci-utility/pkg/semantic"
+)
+
+// deepenSteps are the depths a shallow clone is deepened by, in turn, until a tag is reachable
+// from HEAD. If none is found the rest of the history is fetched.
+var deepenSteps = []int{50, 250, 1000}
+
+// shallowHint explains how to get enough history in CI.
+const shallowHint = "use --fetch-tags, or clone with full history and tags (eg fetch-depth: 0 for actions/checkout)"
+
+// prepareHistory makes sure the tags and history needed to find the latest version are present.
+// If fetch is set the tags are fetched from the remote and a shallow clone is deepened until a tag
+// is reachable from HEAD, otherwise a shallow clone is only reported. It returns whether the
+// repository is (still) shallow.
+func prepareHistory(ctx context.Context, repo Repository, remote string, fetch bool) (bool, error) {
+	shallow, err := repo.IsShallow()
+	if err != nil {
+		return false, err
+	}
+	if !fetch {
+		if shallow {
+			slog.WarnContext(ctx, "Repository is a shallow clone, the latest tag may be missing or out of date; "+shallowHint)
+		}
+		return shallow, nil
+	}
+
+	slog.InfoContext(ctx, "Fetching tags", "remote", remote, "shallow", shallow)
+	if err := repo.FetchTags(remote, 0); err != nil {
+		return shallow, err
+	}
+	if !shallow {
+		return false, nil
+	}
+	for _, depth := range deepenSteps {
+		if hasReachableTag(repo) {
+			return true, nil
+		}
+		slog.InfoContext(ctx, "Deepening shallow clone", "remote", remote, "deepen", depth)
+		if err := repo.FetchTags(remote, depth); err != nil {
+			return true, err
+		}
+		if shallow, err = repo.IsShallow(); err != nil || !shallow {
+			return shallow, err
+		}
+	}
+	if hasReachableTag(repo) {
+		return true, nil
+	}
+	slog.InfoContext(ctx, "No tag found in shallow history, fetching all history", "remote", remote)
+	if err := repo.FetchTags(remote, Unshallow); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+// hasReachableTag returns true if any tag is reachable from HEAD.
+func hasReachableTag(repo Repository) bool {
+	tags, err := repo.Tags("HEAD", "")
+	return err == nil && len(tags) > 0
+}
+
+// missingTags returns the tags on the remote which do not exist locally.
+func missingTags(repo Repository, remote string) ([]string, error) {
+	onRemote, err := repo.RemoteTags(remote)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := repo.Tags("", "")
+	if err != nil {
+		return nil, err
+	}
+	local := map[string]bool{}
+	for _, tag := range tags {
+		local[tag] = true
+	}
+	missing := []string{}
+	for _, tag := range onRemote {
+		if !local[tag] {
+			missing = append(missing, tag)
+		}
+	}
+	return missing, nil
+}
+
+// checkMissingNewerTags returns an error if the remote has tags which are missing locally and have
+// a higher version than current, with the same prefix and allowed by the branch policy. Tagging from
+// such a checkout would reuse a version that has already been released. If the remote cannot be
+// reached the check is skipped with a warning.
+func checkMissingNewerTags(ctx context.Context, repo Repository, remote, prefix string, policy branchPolicy, current semantic.Version) error {
+	missing, err := missingTags(repo, remote)
+	if err != nil {
+		slog.WarnContext(ctx, "Cannot check for missing tags", "remote", remote, "error", err)
+		return nil
+	}
+	for _, tag := range missing {
+		tagPrefix, _, version, err := semantic.ExtractVersionFromTag(tag)
+		if err != nil || tagPrefix != prefix || !policy.allowsVersion(version) {
+			continue
+		}
+		if version.IsGreaterThan(current) {
+			return fmt.Errorf("tag %s on %s is newer than %s but missing locally; %s", tag, remote, current, shallowHint)
+		}
+	}
+	return nil
+}
+
+// diagnoseHistory adds the likely cause to an error from finding the latest tag: a shallow clone
+// or tags which exist on the remote but were not fetched.
+func diagnoseHistory(repo Repository, cause error, remote string, shallow bool) error {
+	reasons := []string{}
+	if shallow {
+		reasons = append(reasons, "the repository is a shallow clone")
+	}
+	if missing, err := missingTags(repo, remote); err == nil && len(missing) > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d tag(s) on %s are missing locally (eg %s)", len(missing), remote, missing[0]))
+	}
+	if len(reasons) == 0 {
+		return cause
+	}
+	return fmt.Errorf("%w: %s; %s", cause, strings.Join(reasons, " and "), shallowHint)
+}
@@ -20,12 +20,15 @@ func AddCommandsTo(parent cmd.Command) error {
 		"suggest-build-env",
 		"Get the environment variables for the current build",
 		executeSuggestBuildEnv,
-		&SuggestBuildEnvOptions{},
+		&SuggestBuildEnvOptions{
+			Remote:        "origin",
+			VersionPrefix: "v",
+		},
 	)
 	// Define the subcommand for updating git tags automatically.
 	updateTag := cmd.NewCommand(
 		"update-tag",
-		"Automatically increment Git tags based on commit messages (e.g., fix:, feat:, breaking:)",
+		"Automatically increment Git tags based on commit messages (e.g., fix:, feat:, breaking:), optionally as pre-releases",
 		executeBumpGitTag,
 		&BumpGitTagOptions{
 			Remote: "origin",
@@ -33,8 +36,63 @@ func AddCommandsTo(parent cmd.Command) error {
 		},
 	)
 
+	// Define the subcommand for generating a changelog from commit messages.
+	changelog := cmd.NewCommand(
+		"changelog",
+		"Generate a changelog from conventional commits since the last tag",
+		executeChangelog,
+		&ChangelogOptions{
+			To:     "HEAD",
+			Prefix: "v",
+			Format: "markdown",
+		},
+	)
+
+	// Define the subcommand for checking commit messages against the conventional commit rules.
+	lintCommits := cmd.NewCommand(
+		"lint-commits",
+		"Check the commit messages in a range (eg origin/main..HEAD) follow the conventional commit rules",
+		executeLintCommits,
+		&LintCommitsOptions{
+			Format: "text",
+		},
+	)
+
+	// Define the subcommand for listing the Go packages affected by the changes on a branch.
+	changed := cmd.NewCommand(
+		"changed",
+		"List the Go packages changed since a base ref (eg origin/main), and the packages which import them",
+		executeChanged,
+		&ChangedOptions{
+			Remote: "origin",
+			Format: "text",
+			Name:   "PACKAGE",
+		},
+	)
+
+	// Define the subcommands for installing git hooks which run the checks locally.
+	hooks := cmd.NewCommand(
+		"hooks",
+		"Manage git hooks which lint commit messages and check version bumps before pushing",
+		nil,
+		&cmd.NoopOptions{},
+	)
+	hooksInstall := cmd.NewCommand(
+		"install",
+		"Install commit-msg and pre-push hooks, chaining any existing hooks",
+		executeHooksInstall,
+		&HooksOptions{},
+	)
+	hooksUninstall := cmd.NewCommand(
+		"uninstall",
+		"Remove the hooks installed by install and restore any chained hooks",
+		executeHooksUninstall,
+		&HooksOptions{},
+	)
+	hooks.SubCommands().MustAdd(hooksInstall, hooksUninstall)
+
 	// Add subcommands to the root git command.
-	gitCommand.SubCommands().MustAdd(suggestBuildEnv, updateTag)
+	gitCommand.SubCommands().MustAdd(suggestBuildEnv, updateTag, changelog, lintCommits, changed, hooks)
 	parent.SubCommands().MustAdd(gitCommand)
 	return nil
 }
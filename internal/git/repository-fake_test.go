@@ -0,0 +1,351 @@
+package git
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidjspooner/ci-utility/pkg/semantic"
+)
+
+// fakeRepository is an in-memory Repository for tests. The history is scripted with commit,
+// checkout and tag, and the tags which are created, pushed and fetched are recorded.
+type fakeRepository struct {
+	commits    map[string]*fakeCommit
+	branches   map[string]string // branch name to commit hash
+	tags       map[string]string // local tag name to commit hash
+	remoteTags map[string]string // tag name to commit hash on the remote
+	config     map[string]string
+	head       string // checked out branch, or a commit hash if detached
+	shallow    bool
+	root       string // root of the working tree, if any
+
+	created []string // tags created by CreateTag
+	options []TagOptions
+	pushed  []string // "<remote> <tag>" for each PushTag
+	fetches []int    // deepen argument of each FetchTags
+}
+
+// fakeCommit is a commit in a fakeRepository.
+type fakeCommit struct {
+	hash    string
+	seq     int // order the commit was made in, used to list commits newest first
+	message string
+	parents []string
+	paths   []string // files changed by the commit
+}
+
+// newFakeRepository returns an empty repository with main checked out.
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		commits:    map[string]*fakeCommit{},
+		branches:   map[string]string{},
+		tags:       map[string]string{},
+		remoteTags: map[string]string{},
+		config:     map[string]string{},
+		head:       "main",
+	}
+}
+
+// commit adds a commit on top of HEAD which changes paths, and returns its hash.
+func (r *fakeRepository) commit(message string, paths ...string) string {
+	seq := len(r.commits) + 1
+	sum := sha1.Sum([]byte(strconv.Itoa(seq) + message))
+	c := &fakeCommit{hash: hex.EncodeToString(sum[:]), seq: seq, message: message, paths: paths}
+	if parent, ok := r.resolve("HEAD"); ok {
+		c.parents = []string{parent}
+	}
+	r.commits[c.hash] = c
+	if _, ok := r.commits[r.head]; ok {
+		r.head = c.hash
+	} else {
+		r.branches[r.head] = c.hash
+	}
+	return c.hash
+}
+
+// merge adds a merge commit of the branch into HEAD and returns its hash.
+func (r *fakeRepository) merge(branch string) string {
+	other := r.branches[branch]
+	hash := r.commit("Merge branch '" + branch + "'")
+	r.commits[hash].parents = append(r.commits[hash].parents, other)
+	return hash
+}
+
+// checkout switches to a branch, creating it at HEAD if it does not exist yet.
+func (r *fakeRepository) checkout(branch string) {
+	if _, ok := r.branches[branch]; !ok {
+		r.branches[branch], _ = r.resolve("HEAD")
+	}
+	r.head = branch
+}
+
+// detach checks out a revision with a detached HEAD.
+func (r *fakeRepository) detach(rev string) {
+	r.head, _ = r.resolve(rev)
+}
+
+// tag tags HEAD locally and on the remote, as if it had been released earlier.
+func (r *fakeRepository) tag(name string) {
+	hash, _ := r.resolve("HEAD")
+	r.tags[name] = hash
+	r.remoteTags[name] = hash
+}
+
+// resolve returns the commit hash for a branch, tag, hash or HEAD, optionally followed by ^ or ~N.
+func (r *fakeRepository) resolve(rev string) (string, bool) {
+	base, steps := rev, 0
+	if i := strings.IndexAny(rev, "^~"); i >= 0 {
+		base = rev[:i]
+		suffix := rev[i:]
+		switch {
+		case suffix == "^":
+			steps = 1
+		case strings.HasPrefix(suffix, "~"):
+			n, err := strconv.Atoi(suffix[1:])
+			if err != nil {
+				return "", false
+			}
+			steps = n
+		default:
+			return "", false
+		}
+	}
+
+	hash := ""
+	switch {
+	case base == "HEAD":
+		if h, ok := r.branches[r.head]; ok {
+			hash = h
+		} else if _, ok := r.commits[r.head]; ok {
+			hash = r.head
+		}
+	case r.branches[base] != "":
+		hash = r.branches[base]
+	case r.tags[base] != "":
+		hash = r.tags[base]
+	case r.commits[base] != nil:
+		hash = base
+	}
+	if hash == "" {
+		return "", false
+	}
+	for ; steps > 0; steps-- {
+		parents := r.commits[hash].parents
+		if len(parents) == 0 {
+			return "", false
+		}
+		hash = parents[0]
+	}
+	return hash, true
+}
+
+// reachable returns the set of commits reachable from a revision.
+func (r *fakeRepository) reachable(rev string) (map[string]bool, error) {
+	seen := map[string]bool{}
+	if rev == "" {
+		return seen, nil
+	}
+	hash, ok := r.resolve(rev)
+	if !ok {
+		return nil, fmt.Errorf("unknown revision %s", rev)
+	}
+	stack := []string{hash}
+	for len(stack) > 0 {
+		hash, stack = stack[len(stack)-1], stack[:len(stack)-1]
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		stack = append(stack, r.commits[hash].parents...)
+	}
+	return seen, nil
+}
+
+// rangeCommits returns the commits in "from..to" or reachable from a single revision, newest first.
+func (r *fakeRepository) rangeCommits(revisionRange string) ([]*fakeCommit, error) {
+	from, to, ok := strings.Cut(revisionRange, "..")
+	if !ok {
+		from, to = "", revisionRange
+	}
+	excluded, err := r.reachable(from)
+	if err != nil {
+		return nil, err
+	}
+	included, err := r.reachable(to)
+	if err != nil {
+		return nil, err
+	}
+	commits := []*fakeCommit{}
+	for hash := range included {
+		if !excluded[hash] {
+			commits = append(commits, r.commits[hash])
+		}
+	}
+	sort.Slice(commits, func(i, j int) bool { return commits[i].seq > commits[j].seq })
+	return commits, nil
+}
+
+// CurrentBranch returns the checked out branch, or "HEAD" if HEAD is detached.
+func (r *fakeRepository) CurrentBranch() (string, error) {
+	if _, ok := r.commits[r.head]; ok {
+		return "HEAD", nil
+	}
+	return r.head, nil
+}
+
+// ShortHash returns the first 7 characters of the commit hash of a revision.
+func (r *fakeRepository) ShortHash(rev string) (string, error) {
+	hash, ok := r.resolve(rev)
+	if !ok {
+		return "", fmt.Errorf("unknown revision %s", rev)
+	}
+	return hash[:7], nil
+}
+
+// Tags returns the sorted tags reachable from rev, or all tags, matching a "prefix*" pattern if set.
+func (r *fakeRepository) Tags(rev, pattern string) ([]string, error) {
+	reachable, err := r.reachable(rev)
+	if err != nil {
+		return nil, err
+	}
+	tags := []string{}
+	for name, hash := range r.tags {
+		if rev != "" && !reachable[hash] {
+			continue
+		}
+		if pattern != "" && !strings.HasPrefix(name, strings.TrimSuffix(pattern, "*")) {
+			continue
+		}
+		tags = append(tags, name)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// CreateTag tags HEAD and records the tag and its options.
+func (r *fakeRepository) CreateTag(name string, options TagOptions) error {
+	if _, ok := r.tags[name]; ok {
+		return fmt.Errorf("tag %s already exists", name)
+	}
+	r.tags[name], _ = r.resolve("HEAD")
+	r.created = append(r.created, name)
+	r.options = append(r.options, options)
+	return nil
+}
+
+// RemoteTags returns the sorted tags on the remote.
+func (r *fakeRepository) RemoteTags(remote string) ([]string, error) {
+	tags := []string{}
+	for name := range r.remoteTags {
+		tags = append(tags, name)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// PushTag copies a local tag to the remote and records the push.
+func (r *fakeRepository) PushTag(remote, name string) error {
+	r.remoteTags[name] = r.tags[name]
+	r.pushed = append(r.pushed, remote+" "+name)
+	return nil
+}
+
+// FetchTags copies the remote tags to the local repository and records the deepen argument.
+// The whole history is always present, so only Unshallow changes the shallow flag.
+func (r *fakeRepository) FetchTags(remote string, deepen int) error {
+	for name, hash := range r.remoteTags {
+		r.tags[name] = hash
+	}
+	if deepen == Unshallow {
+		r.shallow = false
+	}
+	r.fetches = append(r.fetches, deepen)
+	return nil
+}
+
+// Log returns the parsed commits in a revision range which touch any of the paths, newest first.
+func (r *fakeRepository) Log(revisionRange string, paths ...string) ([]semantic.Commit, error) {
+	commits, err := r.rangeCommits(revisionRange)
+	if err != nil {
+		return nil, err
+	}
+	parsed := []semantic.Commit{}
+	for _, c := range commits {
+		if len(paths) > 0 && !c.touches(paths) {
+			continue
+		}
+		commit := semantic.ParseCommit(c.message)
+		commit.Hash = c.hash
+		parsed = append(parsed, commit)
+	}
+	return parsed, nil
+}
+
+// touches returns true if the commit changed a file under any of the paths.
+func (c *fakeCommit) touches(paths []string) bool {
+	for _, changed := range c.paths {
+		for _, p := range paths {
+			if changed == p || strings.HasPrefix(changed, p+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CountCommits returns the number of commits in a revision range.
+func (r *fakeRepository) CountCommits(revisionRange string) (int, error) {
+	commits, err := r.rangeCommits(revisionRange)
+	return len(commits), err
+}
+
+// ChangedFiles returns the sorted paths changed by the commits in base..HEAD.
+func (r *fakeRepository) ChangedFiles(base string) ([]string, error) {
+	commits, err := r.rangeCommits(base + "..HEAD")
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, c := range commits {
+		for _, p := range c.paths {
+			if !slices.Contains(files, p) {
+				files = append(files, p)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// IsAncestor returns true if ancestor is reachable from rev.
+func (r *fakeRepository) IsAncestor(ancestor, rev string) (bool, error) {
+	hash, ok := r.resolve(ancestor)
+	if !ok {
+		return false, fmt.Errorf("unknown revision %s", ancestor)
+	}
+	reachable, err := r.reachable(rev)
+	return reachable[hash], err
+}
+
+// IsShallow returns true if the repository has been marked as a shallow clone.
+func (r *fakeRepository) IsShallow() (bool, error) {
+	return r.shallow, nil
+}
+
+// TopLevel returns the scripted root of the working tree.
+func (r *fakeRepository) TopLevel() (string, error) {
+	if r.root == "" {
+		return "", fmt.Errorf("not a working tree")
+	}
+	return r.root, nil
+}
+
+// Config returns a scripted config value, or "" if it is not set.
+func (r *fakeRepository) Config(key string) (string, error) {
+	return r.config[key], nil
+}
@@ -0,0 +1,258 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/davidjspooner/ci-utility/pkg/semantic"
+)
+
+// Repository is the git repository the commands read branches, tags, history and config from,
+// and create tags in. ExecRepository runs the git command line; tests use a scripted fake.
+type Repository interface {
+	// CurrentBranch returns the checked out branch, or "HEAD" if HEAD is detached.
+	CurrentBranch() (string, error)
+	// ShortHash returns the abbreviated commit hash of a revision.
+	ShortHash(rev string) (string, error)
+
+	// Tags returns the tags reachable from rev, or all tags if rev is empty.
+	// If pattern is set only tags matching the glob pattern are returned.
+	Tags(rev, pattern string) ([]string, error)
+	// CreateTag creates a tag on HEAD.
+	CreateTag(name string, options TagOptions) error
+	// RemoteTags returns the names of the tags on a remote.
+	RemoteTags(remote string) ([]string, error)
+	// PushTag pushes a tag to a remote.
+	PushTag(remote, name string) error
+	// FetchTags fetches the tags from a remote. A shallow clone is deepened by deepen commits if
+	// deepen is greater than zero, or converted to a full clone if deepen is Unshallow.
+	FetchTags(remote string, deepen int) error
+
+	// Log returns the commits in a revision range (eg "v1.0.0..HEAD"), newest first.
+	// If paths are given only commits which touch files under those paths are returned.
+	Log(revisionRange string, paths ...string) ([]semantic.Commit, error)
+	// CountCommits returns the number of commits in a revision range.
+	CountCommits(revisionRange string) (int, error)
+	// ChangedFiles returns the files changed on HEAD since it diverged from base, relative to the
+	// current directory. Files outside the current directory are left out.
+	ChangedFiles(base string) ([]string, error)
+	// IsAncestor returns true if ancestor is reachable from rev.
+	IsAncestor(ancestor, rev string) (bool, error)
+	// IsShallow returns true if the repository is a shallow clone.
+	IsShallow() (bool, error)
+	// TopLevel returns the absolute path of the root of the working tree.
+	TopLevel() (string, error)
+
+	// Config returns the value of a config key, or "" if it is not set.
+	Config(key string) (string, error)
+}
+
+// Unshallow is the FetchTags deepen value which fetches all of the history of a shallow clone.
+const Unshallow = -1
+
+// TagOptions controls how a tag is created. Plain lightweight tags are created by default.
+type TagOptions struct {
+	Annotate   bool
+	Message    string // message of an annotated or signed tag
+	SignFormat string // gpg.format value to sign with, eg "openpgp" or "ssh", or "" to not sign
+	SigningKey string // key to sign with, or "" for the user.signingkey config
+}
+
+// ExecRepository is the Repository in the current directory, accessed by running git.
+type ExecRepository struct{}
+
+// Separators used to split the output of git log into commits and fields.
+const (
+	logRecordSeparator = "\x1e"
+	logFieldSeparator  = "\x1f"
+)
+
+// CurrentBranch returns the checked out branch, or "HEAD" if HEAD is detached.
+func (ExecRepository) CurrentBranch() (string, error) {
+	branch, err := Run("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to get current branch: %v", err)
+	}
+	return branch, nil
+}
+
+// ShortHash returns the abbreviated commit hash of a revision.
+func (ExecRepository) ShortHash(rev string) (string, error) {
+	hash, err := Run("rev-parse", "--short", rev)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %v", rev, err)
+	}
+	return hash, nil
+}
+
+// Tags returns the tags reachable from rev, or all tags if rev is empty, matching pattern if set.
+func (ExecRepository) Tags(rev, pattern string) ([]string, error) {
+	args := []string{"tag", "--list"}
+	if pattern != "" {
+		args = append(args, pattern)
+	}
+	if rev != "" {
+		args = append(args, "--merged", rev)
+	}
+	out, err := Run(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %v", err)
+	}
+	return splitLines(out), nil
+}
+
+// CreateTag creates a tag on HEAD, annotated or signed if the options ask for it.
+func (ExecRepository) CreateTag(name string, options TagOptions) error {
+	args := []string{"tag", name}
+	switch {
+	case options.SignFormat != "":
+		args = []string{"-c", "gpg.format=" + options.SignFormat, "tag"}
+		if options.SigningKey != "" {
+			args = append(args, "--local-user", options.SigningKey)
+		} else {
+			args = append(args, "--sign")
+		}
+		args = append(args, "--message", options.Message, name)
+	case options.Annotate:
+		args = []string{"tag", "--annotate", "--message", options.Message, name}
+	}
+	if _, err := Run(args...); err != nil {
+		return fmt.Errorf("failed to create tag: %v", err)
+	}
+	return nil
+}
+
+// RemoteTags returns the names of the tags on a remote.
+func (ExecRepository) RemoteTags(remote string) ([]string, error) {
+	out, err := Run("ls-remote", "--tags", "--refs", remote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags on %s: %v", remote, err)
+	}
+	tags := []string{}
+	for _, line := range splitLines(out) {
+		_, ref, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		tags = append(tags, strings.TrimPrefix(ref, "refs/tags/"))
+	}
+	return tags, nil
+}
+
+// PushTag pushes a tag to a remote.
+func (ExecRepository) PushTag(remote, name string) error {
+	if _, err := Run("push", remote, name); err != nil {
+		return fmt.Errorf("failed to push tag: %v", err)
+	}
+	return nil
+}
+
+// FetchTags fetches the tags from a remote, deepening or unshallowing a shallow clone if asked.
+func (ExecRepository) FetchTags(remote string, deepen int) error {
+	args := []string{"fetch", "--quiet", "--tags"}
+	switch {
+	case deepen == Unshallow:
+		args = append(args, "--unshallow")
+	case deepen > 0:
+		args = append(args, fmt.Sprintf("--deepen=%d", deepen))
+	}
+	if _, err := Run(append(args, remote)...); err != nil {
+		return fmt.Errorf("failed to fetch tags from %s: %v", remote, err)
+	}
+	return nil
+}
+
+// Log returns the parsed commits in a revision range, newest first, optionally limited to paths.
+func (ExecRepository) Log(revisionRange string, paths ...string) ([]semantic.Commit, error) {
+	logArgs := []string{"log", revisionRange, "--pretty=format:%H%x1f%B%x1e"}
+	if len(paths) > 0 {
+		logArgs = append(append(logArgs, "--"), paths...)
+	}
+	out, err := Run(logArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit messages: %v", err)
+	}
+	commits := []semantic.Commit{}
+	for _, record := range strings.Split(out, logRecordSeparator) {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+		hash, message, _ := strings.Cut(record, logFieldSeparator)
+		commit := semantic.ParseCommit(message)
+		commit.Hash = strings.TrimSpace(hash)
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// CountCommits returns the number of commits in a revision range.
+func (ExecRepository) CountCommits(revisionRange string) (int, error) {
+	out, err := Run("rev-list", "--count", revisionRange)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count commits in %s: %v", revisionRange, err)
+	}
+	count, err := strconv.Atoi(out)
+	if err != nil {
+		return 0, fmt.Errorf("invalid commit count %q: %v", out, err)
+	}
+	return count, nil
+}
+
+// ChangedFiles returns the files changed on HEAD since it diverged from base, relative to the current directory.
+func (ExecRepository) ChangedFiles(base string) ([]string, error) {
+	out, err := Run("diff", "--name-only", "--relative", "--no-renames", base+"...HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files changed since %s: %v", base, err)
+	}
+	return splitLines(out), nil
+}
+
+// IsAncestor returns true if ancestor is reachable from rev.
+func (ExecRepository) IsAncestor(ancestor, rev string) (bool, error) {
+	_, err := Run("merge-base", "--is-ancestor", ancestor, rev)
+	if err == nil {
+		return true, nil
+	}
+	// merge-base exits with 1 if it is not an ancestor, and other codes for errors.
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to check if %s is an ancestor of %s: %v", ancestor, rev, err)
+}
+
+// IsShallow returns true if the repository is a shallow clone.
+func (ExecRepository) IsShallow() (bool, error) {
+	out, err := Run("rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return false, fmt.Errorf("failed to check for a shallow clone: %v", err)
+	}
+	return out == "true", nil
+}
+
+// TopLevel returns the absolute path of the root of the working tree.
+func (ExecRepository) TopLevel() (string, error) {
+	out, err := Run("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("failed to find the root of the working tree: %v", err)
+	}
+	return out, nil
+}
+
+// Config returns the value of a config key, or "" if it is not set.
+func (ExecRepository) Config(key string) (string, error) {
+	out, err := Run("config", "--get", key)
+	if err != nil {
+		// git config exits with 1 if the key is not set.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read config %s: %v", key, err)
+	}
+	return out, nil
+}
@@ -15,7 +15,7 @@ func Run(args ...string) (string, error) {
 	cmd.Stdout = &out
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("git %v failed: %v", args, err)
+		return "", fmt.Errorf("git %v failed: %w", args, err)
 	}
 	// Trim any leading or trailing whitespace from the output
 	return strings.TrimSpace(out.String()), nil
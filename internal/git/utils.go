@@ -1,22 +1,115 @@
 package git
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
+
+	"github.com/davidjspooner/ci-utility/pkg/semantic"
 )
 
-// GetBranches returns a list of all branches in the current git repository.
-// It executes the `git branch` command and returns the branch names as a slice of strings.
+// GetCurrentBranch returns the checked out branch of the repository in the current directory,
+// or "HEAD" if HEAD is detached.
 func GetCurrentBranch() (string, error) {
-	branch, err := Run("rev-parse", "--abbrev-ref", "HEAD")
+	return ExecRepository{}.CurrentBranch()
+}
+
+// ConfigFile returns path, or if it is empty the config file at the root of the repository in the
+// current directory (see configFile).
+func ConfigFile(path string) string {
+	return configFile(ExecRepository{}, path)
+}
+
+// configFile returns path, or if it is empty semantic.DefaultConfigFile at the root of the
+// repository, so that it is found from any subdirectory. Outside a repository the current
+// directory is used. It returns "" if path is empty and there is no config file, so that the
+// built-in defaults are used.
+func configFile(repo Repository, path string) string {
+	if path != "" {
+		return path
+	}
+	root, err := repo.TopLevel()
 	if err != nil {
-		return "", fmt.Errorf("failed to get current branch: %v", err)
+		root = "."
 	}
-	return branch, nil
+	path = filepath.Join(root, semantic.DefaultConfigFile)
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return ""
+	}
+	return path
 }
 
 // splitLines splits the output of a git command into lines.
-// It trims any leading or trailing whitespace and returns a slice of strings.
+// It trims any leading or trailing whitespace from each line and leaves out empty lines.
 func splitLines(output string) []string {
-	return strings.Split(strings.TrimSpace(output), "\n")
+	lines := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// keyValue is a single named value for output.
+type keyValue struct {
+	Key   string
+	Value string
+}
+
+// shellQuote quotes a value so that it is safe to use as a single word in a POSIX shell.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("@%+=:,./_-", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// appendToGithubFile appends values to the file named by a GitHub Actions environment variable
+// such as GITHUB_OUTPUT or GITHUB_ENV. Multi-line values use the heredoc style delimiter syntax.
+func appendToGithubFile(envVar string, values []keyValue) error {
+	fileName := os.Getenv(envVar)
+	if fileName == "" {
+		return fmt.Errorf("$%s is not set", envVar)
+	}
+	f, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open $%s: %w", envVar, err)
+	}
+	defer f.Close()
+
+	for _, kv := range values {
+		if !strings.ContainsAny(kv.Value, "\r\n") {
+			_, err = fmt.Fprintf(f, "%s=%s\n", kv.Key, kv.Value)
+		} else {
+			delimiter, derr := randomDelimiter()
+			if derr != nil {
+				return derr
+			}
+			_, err = fmt.Fprintf(f, "%s<<%s\n%s\n%s\n", kv.Key, delimiter, kv.Value, delimiter)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write to $%s: %w", envVar, err)
+		}
+	}
+	return nil
+}
+
+// randomDelimiter returns a delimiter for multi-line values which cannot appear in the value by accident.
+func randomDelimiter() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate delimiter: %w", err)
+	}
+	return "ghadelimiter_" + hex.EncodeToString(b), nil
 }
@@ -13,8 +13,10 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Client is a GitHub API client for interacting with the GitHub REST API.
@@ -24,28 +26,108 @@ type Client struct {
 	Token      string
 	Owner      string
 	Repo       string
+
+	MaxRetries int           // retries of a failed request, 0 for the default of 3 or negative for none
+	MaxWait    time.Duration // longest wait for a rate limit to reset before giving up, 0 for the default of 10 minutes
 }
 
 // Do sends an HTTP request to the GitHub API and decodes the response.
-// It handles authentication, headers, and error responses.
+// It handles authentication, headers, and error responses, and retries requests which fail
+// with a server error or hit a rate limit (see send).
 func (c *Client) Do(ctx context.Context, method, fullURL string, body io.Reader, headers http.Header, response interface{}) error {
+	_, err := c.do(ctx, method, fullURL, body, headers, response)
+	return err
+}
+
+// do sends a request like Do, and returns the response headers so that callers can follow links.
+func (c *Client) do(ctx context.Context, method, fullURL string, body io.Reader, headers http.Header, response interface{}) (http.Header, error) {
+	resp, err := c.send(ctx, method, fullURL, body, headers)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Decode the response body if a response object is provided.
+	if response != nil {
+		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// send sends an HTTP request to the GitHub API and returns the successful response, whose body
+// the caller must close. Non-2xx responses are returned as an *Error. Network errors, server
+// errors and rate limits are retried with backoff, as long as the body can be sent again and the
+// request can be repeated (see canRepeat).
+func (c *Client) send(ctx context.Context, method, fullURL string, body io.Reader, headers http.Header) (*http.Response, error) {
 	// Replace placeholders in the URL with owner and repo.
 	fullURL = strings.ReplaceAll(fullURL, "{owner}", url.PathEscape(c.Owner))
 	fullURL = strings.ReplaceAll(fullURL, "{repo}", url.PathEscape(c.Repo))
 	if !strings.HasPrefix(fullURL, "http") {
-		fullURL = path.Join(c.BaseURL, fullURL)
+		fullURL = strings.TrimSuffix(c.BaseURL, "/") + "/" + strings.TrimPrefix(fullURL, "/")
 	}
 
-	// Create the HTTP request.
-	req, err := http.NewRequest(method, fullURL, body)
+	// Each attempt sends the body from where it started, as net/http consumes and closes it.
+	rewind, err := rewindableBody(body)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
+	for attempt := 0; ; attempt++ {
+		attemptBody := body
+		if rewind != nil {
+			if attemptBody, err = rewind(); err != nil {
+				return nil, err
+			}
+		}
+		req, err := c.newRequest(ctx, method, fullURL, attemptBody, headers)
+		if err != nil {
+			return nil, err
+		}
+		if rewind != nil {
+			// Used by net/http to send the body again after a redirect.
+			req.GetBody = func() (io.ReadCloser, error) {
+				b, err := rewind()
+				if err != nil {
+					return nil, err
+				}
+				return io.NopCloser(b), nil
+			}
+		}
+		resp, err := c.sendOnce(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
 
-	// Set authentication and accept headers.
+		// Decide whether and when to try again.
+		wait, retry := c.retryDelay(ctx, attempt, resp, err)
+		if !retry || !canResend(body) || !canRepeat(ctx, method, resp, err) {
+			return nil, err
+		}
+		slog.WarnContext(ctx, "Retrying GitHub API request",
+			"method", method,
+			"url", fullURL,
+			"attempt", attempt+1,
+			"wait", wait.Round(time.Second),
+			"error", err,
+		)
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// newRequest creates an HTTP request with the authentication and accept headers, which the
+// given headers may override.
+func (c *Client) newRequest(ctx context.Context, method, fullURL string, body io.Reader, headers http.Header) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
 	req.Header.Set("Authorization", "Bearer "+c.Token)
 	req.Header.Set("Accept", "application/vnd.github+json")
 	for key, value := range headers {
+		req.Header.Del(key)
 		for _, v := range value {
 			if key == "Content-Length" {
 				req.ContentLength, _ = strconv.ParseInt(v, 10, 64)
@@ -54,30 +136,28 @@ func (c *Client) Do(ctx context.Context, method, fullURL string, body io.Reader,
 			req.Header.Add(key, v)
 		}
 	}
+	return req, nil
+}
 
+// sendOnce sends a single HTTP request. If the response is not 2xx, its body is decoded into an
+// *Error and closed, and the response is returned along with the error for its headers.
+func (c *Client) sendOnce(ctx context.Context, req *http.Request) (*http.Response, error) {
 	// Send the HTTP request.
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("HTTP request failed: %w", err)
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
-	defer resp.Body.Close()
+	logRateLimit(ctx, resp)
 
 	// Handle non-2xx responses as errors.
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
 		var ghErr Error
 		ghErr.StatusCode = resp.StatusCode
 		_ = json.NewDecoder(resp.Body).Decode(&ghErr)
-		return &ghErr
+		return resp, &ghErr
 	}
-
-	// Decode the response body if a response object is provided.
-	if response != nil {
-		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
-			return fmt.Errorf("failed to decode response: %w", err)
-		}
-	}
-
-	return nil
+	return resp, nil
 }
 
 // DoJSON sends a JSON-encoded HTTP request to the GitHub API and decodes the JSON response.
@@ -138,11 +218,93 @@ func (c *Client) PutJSON(ctx context.Context, path string, request interface{},
 	return c.DoJSON(ctx, http.MethodPut, path, request, response)
 }
 
+// PatchJSON sends a PATCH request with a JSON body and decodes the JSON response.
+func (c *Client) PatchJSON(ctx context.Context, path string, request interface{}, response interface{}) error {
+	return c.DoJSON(ctx, http.MethodPatch, path, request, response)
+}
+
 // DeleteJSON sends a DELETE request and decodes the JSON response.
 func (c *Client) DeleteJSON(ctx context.Context, path string, response interface{}) error {
 	return c.DoJSON(ctx, http.MethodDelete, path, nil, response)
 }
 
+// List sends GET requests to a list endpoint which returns a JSON array, following the
+// Link rel="next" header, and appends the items of every page to items, which must be a
+// pointer to a slice. Up to 100 items are requested per page unless path sets per_page.
+func (c *Client) List(ctx context.Context, path string, items interface{}) error {
+	target := reflect.ValueOf(items)
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("items must be a pointer to a slice, got %T", items)
+	}
+	return c.forEachPage(ctx, path,
+		func() interface{} { return reflect.New(target.Elem().Type()).Interface() },
+		func(page interface{}) int {
+			values := reflect.ValueOf(page).Elem()
+			target.Elem().Set(reflect.AppendSlice(target.Elem(), values))
+			return values.Len()
+		},
+	)
+}
+
+// forEachPage sends GET requests to a paginated endpoint, following the Link rel="next" header.
+// Each page is decoded into a value from newPage and passed to add, which returns the number of
+// items on the page. Up to 100 items are requested per page unless path sets per_page.
+func (c *Client) forEachPage(ctx context.Context, path string, newPage func() interface{}, add func(page interface{}) int) error {
+	if !strings.Contains(path, "per_page=") {
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		path += separator + "per_page=100"
+	}
+
+	next := c.BaseURL + path
+	total := 0
+	for pages := 1; next != ""; pages++ {
+		page := newPage()
+		header, err := c.do(ctx, http.MethodGet, next, nil, nil, page)
+		if err != nil {
+			slog.WarnContext(ctx, "GitHub API list request failed", "path", path, "page", pages, "url", next, "error", err)
+			return err
+		}
+		items := add(page)
+		total += items
+		slog.DebugContext(ctx, "GitHub API list request", "path", path, "page", pages, "items", items, "total", total)
+
+		// Stop if the next page would be the same page again.
+		link := nextLink(header.Get("Link"))
+		if link == next {
+			break
+		}
+		next = link
+	}
+	return nil
+}
+
+// nextLink returns the URL with rel="next" in a Link header, or "" if there is none, eg
+// <https://api.github.com/repositories/1/pulls/2/commits?page=2>; rel="next", <...>; rel="last".
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if name != "rel" {
+				continue
+			}
+			// rel may list several space separated relations.
+			for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+				if rel == "next" {
+					return strings.Trim(strings.TrimSpace(target), "<>")
+				}
+			}
+		}
+	}
+	return ""
+}
+
 // UploadMeta contains metadata for uploading a release asset to GitHub.
 type UploadMeta struct {
 	Name        string
@@ -239,34 +401,18 @@ func (c *Client) UploadBinaryStream(ctx context.Context, releaseID int64, meta U
 
 // DownloadBinary downloads a binary file from the given URL using the GitHub API.
 func (c *Client) DownloadBinary(ctx context.Context, fullURL string) (io.ReadCloser, error) {
-    // DownloadBinary fetches a binary file from the provided URL using the GitHub API.
-    // Add debug logging of request/response details.
-    slog.DebugContext(ctx, "Downloading binary", "url", fullURL)
-	req, err := http.NewRequest(http.MethodGet, fullURL, nil)
+	// Add debug logging of request/response details.
+	slog.DebugContext(ctx, "Downloading binary", "url", fullURL)
+	headers := http.Header{
+		"Accept": []string{"application/octet-stream"},
+	}
+	resp, err := c.send(ctx, http.MethodGet, fullURL, nil, headers)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create download request: %w", err)
+		slog.WarnContext(ctx, "Download request failed", "url", fullURL, "error", err)
+		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.Token)
-	req.Header.Set("Accept", "application/octet-stream")
-
-    resp, err := c.HTTPClient.Do(req)
-    if err != nil {
-        slog.WarnContext(ctx, "Download request failed", "url", fullURL, "error", err)
-        return nil, fmt.Errorf("download request failed: %w", err)
-    }
-
-	// Check for non-OK status code.
-    if resp.StatusCode != http.StatusOK {
-        slog.WarnContext(ctx, "Download returned non-OK status", "url", fullURL, "status", resp.StatusCode)
-        defer resp.Body.Close()
-        var ghErr Error
-        ghErr.StatusCode = resp.StatusCode
-        _ = json.NewDecoder(resp.Body).Decode(&ghErr)
-        return nil, &ghErr
-    }
-
-    // Return the response body for reading.
-    slog.DebugContext(ctx, "Downloaded binary", "url", fullURL, "status", resp.StatusCode, "content_length", resp.ContentLength)
-    return resp.Body, nil
+	// Return the response body for reading.
+	slog.DebugContext(ctx, "Downloaded binary", "url", fullURL, "status", resp.StatusCode, "content_length", resp.ContentLength)
+	return resp.Body, nil
 }
@@ -0,0 +1,188 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer serves the responses in order, one per request, and records the requests.
+type testServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses []func(w http.ResponseWriter, r *http.Request)
+	requests  []string // "<method> <path> <body>" of each request
+}
+
+// newTestServer starts a server which sends responses in order, and a client for it.
+func newTestServer(t *testing.T, responses ...func(w http.ResponseWriter, r *http.Request)) (*testServer, *Client) {
+	s := &testServer{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		n := len(s.requests)
+		s.requests = append(s.requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.RequestURI(), body))
+		s.mu.Unlock()
+		if n >= len(s.responses) {
+			t.Errorf("unexpected request %d: %s %s", n+1, r.Method, r.URL)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.responses[n](w, r)
+	}))
+	t.Cleanup(s.Close)
+	client := &Client{
+		HTTPClient: s.Client(),
+		BaseURL:    s.URL + "/",
+		Token:      "token",
+		Owner:      "owner",
+		Repo:       "repo",
+	}
+	return s, client
+}
+
+// reply returns a response with a status code, headers given as name and value pairs, and a body.
+func reply(status int, body string, headers ...string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	s, client := newTestServer(t,
+		reply(http.StatusBadGateway, `{"message":"bad gateway"}`, "Retry-After", "0"),
+		reply(http.StatusOK, `{"id":1}`),
+	)
+	var response struct{ ID int }
+	if err := client.PutJSON(context.Background(), "repos/{owner}/{repo}/thing", map[string]string{"name": "x"}, &response); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.ID != 1 {
+		t.Errorf("expected the response of the retry, got %+v", response)
+	}
+	want := `PUT /repos/owner/repo/thing {"name":"x"}`
+	if len(s.requests) != 2 || s.requests[0] != want || s.requests[1] != want {
+		t.Errorf("expected the body to be sent twice as %q, got %q", want, s.requests)
+	}
+}
+
+func TestClientRetriesRateLimits(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Unix(), 10)
+	s, client := newTestServer(t,
+		reply(http.StatusForbidden, `{"message":"API rate limit exceeded"}`, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", reset),
+		reply(http.StatusTooManyRequests, `{"message":"too many requests"}`, "Retry-After", "0"),
+		reply(http.StatusOK, `[]`),
+	)
+	var response []int
+	if err := client.GetJSON(context.Background(), "repos/{owner}/{repo}/things", &response); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(s.requests) != 3 {
+		t.Errorf("expected 3 requests, got %q", s.requests)
+	}
+}
+
+func TestClientDoesNotRetryPermissionErrors(t *testing.T) {
+	s, client := newTestServer(t,
+		reply(http.StatusForbidden, `{"message":"Resource not accessible by integration"}`, "X-RateLimit-Remaining", "4000"),
+	)
+	err := client.GetJSON(context.Background(), "repos/{owner}/{repo}/things", nil)
+	if err == nil || !strings.Contains(err.Error(), "Resource not accessible") {
+		t.Fatalf("expected the permission error, got %v", err)
+	}
+	if len(s.requests) != 1 {
+		t.Errorf("expected 1 request, got %q", s.requests)
+	}
+}
+
+func TestClientPostRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		wantErr      bool
+		wantRequests int
+	}{
+		{name: "not retried by default", ctx: context.Background(), wantErr: true, wantRequests: 1},
+		{name: "retried when marked safe", ctx: WithSafeRetry(context.Background()), wantErr: false, wantRequests: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, client := newTestServer(t,
+				reply(http.StatusBadGateway, `{"message":"bad gateway"}`, "Retry-After", "0"),
+				reply(http.StatusCreated, `{"id":2}`),
+			)
+			err := client.PostJSON(tt.ctx, "repos/{owner}/{repo}/labels", []string{"bug"}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if len(s.requests) != tt.wantRequests {
+				t.Fatalf("expected %d request(s), got %q", tt.wantRequests, s.requests)
+			}
+			for _, request := range s.requests {
+				if request != `POST /repos/owner/repo/labels ["bug"]` {
+					t.Errorf("unexpected request %q", request)
+				}
+			}
+		})
+	}
+}
+
+func TestClientList(t *testing.T) {
+	var s *testServer
+	s, client := newTestServer(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/repo/things?per_page=100&page=2>; rel="next", <%s/repos/owner/repo/things?per_page=100&page=2>; rel="last"`, s.URL, s.URL))
+			io.WriteString(w, `[1,2]`)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/repo/things?per_page=100&page=1>; rel="prev first"`, s.URL))
+			io.WriteString(w, `[3]`)
+		},
+	)
+	var items []int
+	if err := client.List(context.Background(), "repos/{owner}/{repo}/things", &items); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(items) != "[1 2 3]" {
+		t.Errorf("expected the items of both pages, got %v", items)
+	}
+	want := []string{
+		"GET /repos/owner/repo/things?per_page=100 ",
+		"GET /repos/owner/repo/things?per_page=100&page=2 ",
+	}
+	if fmt.Sprint(s.requests) != fmt.Sprint(want) {
+		t.Errorf("expected requests %q, got %q", want, s.requests)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: `<https://api.github.com/x?page=2>; rel="next", <https://api.github.com/x?page=5>; rel="last"`, want: "https://api.github.com/x?page=2"},
+		{header: `<https://api.github.com/x?page=1>; rel="prev", <https://api.github.com/x?page=3>; rel="next"`, want: "https://api.github.com/x?page=3"},
+		{header: `<https://api.github.com/x?page=3>; rel="next last"`, want: "https://api.github.com/x?page=3"},
+		{header: `<https://api.github.com/x?page=1>; rel="first", <https://api.github.com/x?page=4>; rel="prev"`, want: ""},
+		{header: `<https://api.github.com/x?page=2>; title="next"`, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := nextLink(tt.header); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
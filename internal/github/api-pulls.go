@@ -0,0 +1,80 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// maxPullRequestCommits is the most commits the pull request commits endpoint returns, however
+// it is paginated. Larger pull requests are listed with the compare endpoint instead.
+const maxPullRequestCommits = 250
+
+// GetPullRequest returns a pull request.
+func (c *Client) GetPullRequest(ctx context.Context, number int) (*PullRequest, error) {
+	var pr PullRequest
+	if err := c.GetJSON(ctx, fmt.Sprintf("/repos/{owner}/{repo}/pulls/%d", number), &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// UpdatePullRequest updates the title and/or body of a pull request.
+func (c *Client) UpdatePullRequest(ctx context.Context, number int, update UpdatePullRequestRequest) (*PullRequest, error) {
+	var pr PullRequest
+	if err := c.PatchJSON(ctx, fmt.Sprintf("/repos/{owner}/{repo}/pulls/%d", number), update, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// ListPullRequestCommits returns all the commits in a pull request, oldest first.
+func (c *Client) ListPullRequestCommits(ctx context.Context, pr *PullRequest) ([]Commit, error) {
+	if pr.Commits >= maxPullRequestCommits {
+		return c.CompareCommits(ctx, pr.Base.SHA, pr.Head.SHA)
+	}
+	commits := []Commit{}
+	if err := c.List(ctx, fmt.Sprintf("/repos/{owner}/{repo}/pulls/%d/commits", pr.Number), &commits); err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// CompareCommits returns the commits on head since it diverged from base, oldest first.
+func (c *Client) CompareCommits(ctx context.Context, base, head string) ([]Commit, error) {
+	commits := []Commit{}
+	path := fmt.Sprintf("/repos/{owner}/{repo}/compare/%s...%s", url.PathEscape(base), url.PathEscape(head))
+	err := c.forEachPage(ctx, path,
+		func() interface{} { return &CompareResponse{} },
+		func(page interface{}) int {
+			compare := page.(*CompareResponse)
+			commits = append(commits, compare.Commits...)
+			return len(compare.Commits)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// AddLabels adds labels to an issue or pull request, creating any labels which do not exist yet.
+func (c *Client) AddLabels(ctx context.Context, number int, labels ...string) error {
+	request := struct {
+		Labels []string `json:"labels"`
+	}{Labels: labels}
+	// Adding a label which is already set has no effect, so the request can be retried.
+	return c.PostJSON(WithSafeRetry(ctx), fmt.Sprintf("/repos/{owner}/{repo}/issues/%d/labels", number), request, nil)
+}
+
+// RemoveLabel removes a label from an issue or pull request. It is not an error if the label is not set.
+func (c *Client) RemoveLabel(ctx context.Context, number int, label string) error {
+	err := c.DeleteJSON(ctx, fmt.Sprintf("/repos/{owner}/{repo}/issues/%d/labels/%s", number, url.PathEscape(label)), nil)
+	var ghErr *Error
+	if errors.As(err, &ghErr) && ghErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// CreateRelease creates a release.
+func (c *Client) CreateRelease(ctx context.Context, request CreateReleaseRequest) (*ReleaseResponse, error) {
+	var release ReleaseResponse
+	if err := c.PostJSON(ctx, "/repos/{owner}/{repo}/releases", request, &release); err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// UpdateRelease updates the name, body and flags of a release. Fields which are not set in the
+// request are left unchanged.
+func (c *Client) UpdateRelease(ctx context.Context, id int64, request UpdateReleaseRequest) (*ReleaseResponse, error) {
+	var release ReleaseResponse
+	if err := c.PatchJSON(ctx, fmt.Sprintf("/repos/{owner}/{repo}/releases/%d", id), request, &release); err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// GetReleaseByTag returns the release for a tag, or nil if there is none. Draft releases are not
+// returned by the tag endpoint, so the releases are listed to find a draft with the tag.
+func (c *Client) GetReleaseByTag(ctx context.Context, tag string) (*ReleaseResponse, error) {
+	// Not finding the release is expected, so this is not logged as a failure like GetJSON does.
+	var release ReleaseResponse
+	_, err := c.do(ctx, http.MethodGet, c.BaseURL+"/repos/{owner}/{repo}/releases/tags/"+url.PathEscape(tag), nil, nil, &release)
+	if err == nil {
+		return &release, nil
+	}
+	var ghErr *Error
+	if !errors.As(err, &ghErr) || ghErr.StatusCode != http.StatusNotFound {
+		return nil, err
+	}
+
+	releases := []ReleaseResponse{}
+	if err := c.List(ctx, "/repos/{owner}/{repo}/releases", &releases); err != nil {
+		return nil, err
+	}
+	for i := range releases {
+		if releases[i].TagName == tag {
+			return &releases[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ListReleaseAssets returns the assets of a release.
+func (c *Client) ListReleaseAssets(ctx context.Context, releaseID int64) ([]AssetResponse, error) {
+	assets := []AssetResponse{}
+	if err := c.List(ctx, fmt.Sprintf("/repos/{owner}/{repo}/releases/%d/assets", releaseID), &assets); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// DeleteReleaseAsset deletes a release asset.
+func (c *Client) DeleteReleaseAsset(ctx context.Context, assetID int64) error {
+	return c.DeleteJSON(ctx, fmt.Sprintf("/repos/{owner}/{repo}/releases/assets/%d", assetID), nil)
+}
+
+// GenerateReleaseNotes asks GitHub to generate release notes for a tag, listing the pull requests
+// merged since the previous tag. If previousTag is empty GitHub picks the previous release.
+func (c *Client) GenerateReleaseNotes(ctx context.Context, tag, previousTag string) (*GenerateNotesResponse, error) {
+	var notes GenerateNotesResponse
+	request := GenerateNotesRequest{TagName: tag, PreviousTagName: previousTag}
+	// Generating notes does not change anything, so the request can be retried.
+	if err := c.PostJSON(WithSafeRetry(ctx), "/repos/{owner}/{repo}/releases/generate-notes", request, &notes); err != nil {
+		return nil, err
+	}
+	return &notes, nil
+}
@@ -0,0 +1,203 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultMaxRetries is the number of times a failed request is retried if Client.MaxRetries is 0.
+	defaultMaxRetries = 3
+	// defaultMaxWait is the longest wait for a rate limit to reset if Client.MaxWait is 0.
+	defaultMaxWait = 10 * time.Minute
+	// maxBackoff is the longest wait between retries of server and network errors.
+	maxBackoff = 30 * time.Second
+	// secondaryRateLimitWait is how long to wait after hitting a secondary rate limit without a
+	// Retry-After header, as recommended by GitHub.
+	secondaryRateLimitWait = time.Minute
+)
+
+// retryDelay returns how long to wait before retrying a request which failed with err, and
+// whether to retry it at all. resp is nil if no response was received.
+func (c *Client) retryDelay(ctx context.Context, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	maxRetries := c.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	}
+	if attempt >= maxRetries || ctx.Err() != nil {
+		return 0, false
+	}
+
+	var wait time.Duration
+	switch {
+	case resp == nil:
+		// Network errors, eg a connection reset.
+		wait = backoff(attempt)
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		limit, limited := rateLimitDelay(resp, err)
+		switch {
+		case limited:
+			wait = limit
+		case resp.StatusCode == http.StatusTooManyRequests:
+			wait = backoff(attempt)
+		default:
+			// A 403 which is not a rate limit is a permission error.
+			return 0, false
+		}
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+		wait = backoff(attempt)
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			wait = retryAfter
+		}
+	default:
+		return 0, false
+	}
+
+	maxWait := c.MaxWait
+	if maxWait == 0 {
+		maxWait = defaultMaxWait
+	}
+	if wait > maxWait {
+		slog.WarnContext(ctx, "GitHub API rate limit resets too late to wait for", "wait", wait.Round(time.Second), "max_wait", maxWait)
+		return 0, false
+	}
+	return wait, true
+}
+
+// rateLimitDelay returns how long to wait for a rate limit which caused a 403 or 429 response
+// to reset, and false if the response is not a rate limit.
+func rateLimitDelay(resp *http.Response, err error) (time.Duration, bool) {
+	// Secondary rate limits usually say how long to wait.
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		return retryAfter, true
+	}
+	// The primary rate limit resets at a fixed time.
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, parseErr := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); parseErr == nil {
+			return max(time.Until(time.Unix(reset, 0)), 0) + time.Second, true
+		}
+	}
+	var ghErr *Error
+	if errors.As(err, &ghErr) && strings.Contains(strings.ToLower(ghErr.Message), "secondary rate limit") {
+		return secondaryRateLimitWait, true
+	}
+	return 0, false
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// backoff returns an exponential delay with jitter for a retry attempt: about 1s, 2s, 4s and so on.
+func backoff(attempt int) time.Duration {
+	wait := min(time.Second<<attempt, maxBackoff)
+	return wait + rand.N(wait/2+1)
+}
+
+// safeRetryKey is the context key which marks non-idempotent requests as safe to retry.
+type safeRetryKey struct{}
+
+// WithSafeRetry returns a context whose requests are retried after server and network errors even
+// if their method is not idempotent, for POST requests which can be repeated without side effects,
+// eg adding labels or generating release notes.
+func WithSafeRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, safeRetryKey{}, true)
+}
+
+// canRepeat returns true if a failed request may be sent again. Requests other than POST are
+// idempotent. A POST is only repeated if the context marks it as safe, or it was never processed:
+// the connection failed before it was sent, or it was refused by a rate limit. Repeating a POST
+// after a server error could create a release twice or leave a partial asset behind.
+func canRepeat(ctx context.Context, method string, resp *http.Response, err error) bool {
+	if method != http.MethodPost {
+		return true
+	}
+	if safe, _ := ctx.Value(safeRetryKey{}).(bool); safe {
+		return true
+	}
+	if resp == nil {
+		var opErr *net.OpError
+		var dnsErr *net.DNSError
+		return (errors.As(err, &opErr) && opErr.Op == "dial") || errors.As(err, &dnsErr)
+	}
+	// retryDelay only retries a 403 if it is a rate limit.
+	return resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// canResend returns true if a request body can be rewound to send it again.
+func canResend(body io.Reader) bool {
+	if body == nil {
+		return true
+	}
+	_, ok := body.(io.Seeker)
+	return ok
+}
+
+// rewindableBody returns a function which rewinds a request body to where it started and returns
+// it for the next attempt, or nil if the body cannot be sent again. net/http closes the body of
+// every request it sends, so a body which is also an io.Closer, eg an *os.File, is returned
+// without its Close method to keep it open for the next attempt.
+func rewindableBody(body io.Reader) (func() (io.Reader, error), error) {
+	seeker, ok := body.(io.ReadSeeker)
+	if !ok {
+		return nil, nil
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the start of the request body: %w", err)
+	}
+	return func() (io.Reader, error) {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		if _, ok := body.(io.Closer); ok {
+			return struct{ io.Reader }{seeker}, nil
+		}
+		return body, nil
+	}, nil
+}
+
+// sleep waits for a duration, or until the context is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// logRateLimit logs the remaining primary rate limit of a response.
+func logRateLimit(ctx context.Context, resp *http.Response) {
+	remaining := resp.Header.Get("X-RateLimit-Remaining")
+	if remaining == "" {
+		return
+	}
+	slog.DebugContext(ctx, "GitHub API rate limit",
+		"limit", resp.Header.Get("X-RateLimit-Limit"),
+		"remaining", remaining,
+		"resource", resp.Header.Get("X-RateLimit-Resource"),
+		"reset", resp.Header.Get("X-RateLimit-Reset"),
+	)
+}
@@ -0,0 +1,197 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testResponse returns a response with a status code and headers, given as name and value pairs.
+func testResponse(status int, headers ...string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}}
+	for i := 0; i+1 < len(headers); i += 2 {
+		resp.Header.Set(headers[i], headers[i+1])
+	}
+	return resp
+}
+
+func TestRetryDelay(t *testing.T) {
+	inAMinute := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+	inAnHour := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	tests := []struct {
+		name      string
+		attempt   int
+		resp      *http.Response
+		err       error
+		wantRetry bool
+		wantMin   time.Duration
+		wantMax   time.Duration
+	}{
+		{name: "network error backs off", resp: nil, err: errors.New("connection reset"), wantRetry: true, wantMin: time.Second, wantMax: 1500 * time.Millisecond},
+		{name: "backoff grows with the attempt", attempt: 2, resp: nil, err: errors.New("connection reset"), wantRetry: true, wantMin: 4 * time.Second, wantMax: 6 * time.Second},
+		{name: "server error backs off", resp: testResponse(502), wantRetry: true, wantMin: time.Second, wantMax: 1500 * time.Millisecond},
+		{name: "server error with Retry-After", resp: testResponse(503, "Retry-After", "7"), wantRetry: true, wantMin: 7 * time.Second, wantMax: 7 * time.Second},
+		{name: "not implemented is not retried", resp: testResponse(501)},
+		{name: "not found is not retried", resp: testResponse(404)},
+		{name: "forbidden without a rate limit is not retried", resp: testResponse(403, "X-RateLimit-Remaining", "12")},
+		{name: "primary rate limit waits for the reset", resp: testResponse(403, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", inAMinute), wantRetry: true, wantMin: 59 * time.Second, wantMax: 62 * time.Second},
+		{name: "secondary rate limit with Retry-After", resp: testResponse(403, "Retry-After", "30"), wantRetry: true, wantMin: 30 * time.Second, wantMax: 30 * time.Second},
+		{name: "secondary rate limit message", resp: testResponse(403), err: &Error{StatusCode: 403, Message: "You have exceeded a secondary rate limit."}, wantRetry: true, wantMin: time.Minute, wantMax: time.Minute},
+		{name: "too many requests with Retry-After", resp: testResponse(429, "Retry-After", "2"), wantRetry: true, wantMin: 2 * time.Second, wantMax: 2 * time.Second},
+		{name: "too many requests backs off", resp: testResponse(429), wantRetry: true, wantMin: time.Second, wantMax: 1500 * time.Millisecond},
+		{name: "rate limit resets too late", resp: testResponse(403, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", inAnHour)},
+		{name: "out of retries", attempt: 3, resp: testResponse(502)},
+	}
+	client := &Client{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, retry := client.retryDelay(context.Background(), tt.attempt, tt.resp, tt.err)
+			if retry != tt.wantRetry {
+				t.Fatalf("expected retry %v, got %v (wait %v)", tt.wantRetry, retry, wait)
+			}
+			if retry && (wait < tt.wantMin || wait > tt.wantMax) {
+				t.Errorf("expected a wait between %v and %v, got %v", tt.wantMin, tt.wantMax, wait)
+			}
+		})
+	}
+}
+
+func TestRetryDelayLimits(t *testing.T) {
+	resp := testResponse(502)
+	if _, retry := (&Client{MaxRetries: -1}).retryDelay(context.Background(), 0, resp, nil); retry {
+		t.Errorf("expected no retries with negative MaxRetries")
+	}
+	if _, retry := (&Client{MaxRetries: 5}).retryDelay(context.Background(), 4, resp, nil); !retry {
+		t.Errorf("expected a retry within MaxRetries")
+	}
+	limited := testResponse(429, "Retry-After", "120")
+	if _, retry := (&Client{MaxWait: time.Minute}).retryDelay(context.Background(), 0, limited, nil); retry {
+		t.Errorf("expected no retry for a wait longer than MaxWait")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, retry := (&Client{}).retryDelay(ctx, 0, resp, nil); retry {
+		t.Errorf("expected no retry after the context is cancelled")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "", wantOK: false},
+		{value: "0", want: 0, wantOK: true},
+		{value: "120", want: 2 * time.Minute, wantOK: true},
+		{value: "-1", wantOK: false},
+		{value: "soon", wantOK: false},
+		{value: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0, wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("expected %v %v, got %v %v", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+
+	// A date in the future is the time until then.
+	at := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got, ok := parseRetryAfter(at); !ok || got < 59*time.Minute || got > time.Hour {
+		t.Errorf("expected about an hour until %s, got %v %v", at, got, ok)
+	}
+}
+
+func TestCanRepeat(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		resp   *http.Response
+		err    error
+		want   bool
+	}{
+		{name: "GET after a server error", method: http.MethodGet, resp: testResponse(502), want: true},
+		{name: "PUT after a server error", method: http.MethodPut, resp: testResponse(502), want: true},
+		{name: "POST after a server error", method: http.MethodPost, resp: testResponse(502), want: false},
+		{name: "safe POST after a server error", ctx: WithSafeRetry(context.Background()), method: http.MethodPost, resp: testResponse(502), want: true},
+		{name: "POST after a rate limit", method: http.MethodPost, resp: testResponse(429), want: true},
+		{name: "POST after a rate limit forbidden", method: http.MethodPost, resp: testResponse(403), want: true},
+		{name: "POST which failed to connect", method: http.MethodPost, err: dialErr, want: true},
+		{name: "POST with an unknown host", method: http.MethodPost, err: &net.DNSError{Err: "no such host", Name: "api.github.invalid"}, want: true},
+		{name: "POST which failed after it was sent", method: http.MethodPost, err: readErr, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if got := canRepeat(ctx, tt.method, tt.resp, tt.err); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRewindableBody(t *testing.T) {
+	// A body is rewound to where it started, not to the beginning.
+	body := bytes.NewReader([]byte("skip:payload"))
+	if _, err := body.Seek(5, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	rewind, err := rewindableBody(body)
+	if err != nil || rewind == nil {
+		t.Fatalf("expected a rewindable body, got %v", err)
+	}
+	for range 2 {
+		r, err := rewind()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if data, _ := io.ReadAll(r); string(data) != "payload" {
+			t.Errorf("expected payload, got %q", data)
+		}
+	}
+
+	// A file is not closed by net/http between attempts.
+	path := filepath.Join(t.TempDir(), "asset.bin")
+	if err := os.WriteFile(path, []byte("asset"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if rewind, err = rewindableBody(file); err != nil || rewind == nil {
+		t.Fatalf("expected a rewindable file, got %v", err)
+	}
+	r, err := rewind()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := r.(io.Closer); ok {
+		t.Errorf("expected the file to be returned without its Close method")
+	}
+
+	// Other readers cannot be sent again.
+	if rewind, err = rewindableBody(io.LimitReader(file, 1)); err != nil || rewind != nil {
+		t.Errorf("expected no rewind for a reader which cannot seek, got %v", err)
+	}
+	if !canResend(nil) || !canResend(body) || canResend(io.LimitReader(file, 1)) {
+		t.Errorf("expected only empty and seekable bodies to be sent again")
+	}
+}
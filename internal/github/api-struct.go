@@ -8,20 +8,102 @@ type CreateReleaseRequest struct {
 	Body            string `json:"body,omitempty"`
 	Draft           bool   `json:"draft"`
 	Prerelease      bool   `json:"prerelease"`
+
+	GenerateReleaseNotes bool `json:"generate_release_notes,omitempty"` // only supported when creating a release
+}
+
+// UpdateReleaseRequest represents the payload to update a GitHub release. Fields which are not set
+// are left unchanged, so a draft or prerelease stays one unless Draft or Prerelease is set.
+type UpdateReleaseRequest struct {
+	TagName    string `json:"tag_name,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Body       string `json:"body,omitempty"`
+	Draft      *bool  `json:"draft,omitempty"`
+	Prerelease *bool  `json:"prerelease,omitempty"`
+}
+
+// GenerateNotesRequest represents the payload to generate release notes for a tag.
+type GenerateNotesRequest struct {
+	TagName         string `json:"tag_name"`
+	TargetCommitish string `json:"target_commitish,omitempty"`
+	PreviousTagName string `json:"previous_tag_name,omitempty"`
+}
+
+// GenerateNotesResponse represents the release notes generated by GitHub.
+type GenerateNotesResponse struct {
+	Name string `json:"name"`
+	Body string `json:"body"`
 }
 
 // ReleaseResponse represents the response from GitHub after creating a release.
 type ReleaseResponse struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
-	URL  string `json:"html_url"`
+	ID         int64           `json:"id"`
+	TagName    string          `json:"tag_name"`
+	Name       string          `json:"name"`
+	Body       string          `json:"body"`
+	Draft      bool            `json:"draft"`
+	Prerelease bool            `json:"prerelease"`
+	URL        string          `json:"html_url"`
+	UploadURL  string          `json:"upload_url"` // eg "https://uploads.github.com/repos/o/r/releases/1/assets{?name,label}"
+	Assets     []AssetResponse `json:"assets"`
 }
 
 // AssetResponse represents a single asset attached to a GitHub release.
 type AssetResponse struct {
-	ID   int64  `json:"id"`
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	URL    string `json:"browser_download_url"`
+	Size   int64  `json:"size"`
+	Digest string `json:"digest"` // eg "sha256:...", empty for assets uploaded before GitHub recorded digests
+	State  string `json:"state"`  // "uploaded", or "starter" if the upload did not finish
+}
+
+// PullRequest represents a GitHub pull request.
+type PullRequest struct {
+	Number  int            `json:"number"`
+	Title   string         `json:"title"`
+	Body    string         `json:"body"`
+	State   string         `json:"state"`
+	URL     string         `json:"html_url"`
+	Labels  []Label        `json:"labels"`
+	Commits int            `json:"commits"` // number of commits in the pull request
+	Head    PullRequestRef `json:"head"`
+	Base    PullRequestRef `json:"base"`
+}
+
+// PullRequestRef is the head or base branch of a pull request.
+type PullRequestRef struct {
+	Ref string `json:"ref"`
+	SHA string `json:"sha"`
+}
+
+// UpdatePullRequestRequest represents the payload to update a pull request. Empty fields are left unchanged.
+type UpdatePullRequestRequest struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+}
+
+// Label represents a label on an issue or pull request.
+type Label struct {
 	Name string `json:"name"`
-	URL  string `json:"browser_download_url"`
+}
+
+// Commit represents a commit returned by the pulls and compare APIs.
+// The message is nested, eg {"sha": "...", "commit": {"message": "..."}}.
+type Commit struct {
+	SHA    string       `json:"sha"`
+	Commit CommitDetail `json:"commit"`
+}
+
+// CommitDetail holds the git data of a Commit.
+type CommitDetail struct {
+	Message string `json:"message"`
+}
+
+// CompareResponse represents the response from GitHub when comparing two commits.
+type CompareResponse struct {
+	TotalCommits int      `json:"total_commits"`
+	Commits      []Commit `json:"commits"`
 }
 
 //  func main() {
@@ -2,14 +2,12 @@ package github
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
 	"log/slog"
-	"net/http"
-	"os"
+	"strconv"
 	"strings"
 
+	"github.com/davidjspooner/ci-utility/internal/git"
 	"github.com/davidjspooner/ci-utility/pkg/semantic"
 )
 
@@ -18,10 +16,25 @@ type PRUpdateOptions struct {
 	// PRNumber is the pull request number.
 	PRNumber string `flag:"<pr-number>,Pull request number"`
 	// DryRun indicates whether to perform a dry run (no actual updates).
-	DryRun bool `flag:"--dry-run,Do not update the PR title"`
+	DryRun bool `flag:"--dry-run,Do not update the PR"`
+	// BumpRules is a YAML file with bump rules.
+	BumpRules string `flag:"--bump-rules,YAML file with bump rules (defaults to .ci-utility.yaml at the repository root if present)"`
+	// Label adds a label for the bump, replacing the labels for other bumps.
+	Label bool `flag:"--label,Label the PR with the bump (eg bump:minor), replacing any other bump label"`
+	// LabelPrefix is the prefix of the bump labels.
+	LabelPrefix string `flag:"--label-prefix,Prefix of the bump labels"`
+	// Body adds or updates a section of the PR body describing the bump.
+	Body bool `flag:"--body,Add or update a section of the PR body describing the bump"`
 }
 
-// executeUpdateGithubPRMeta updates the metadata (title) of a GitHub PR based on commit messages.
+// Markers around the section of the PR body which is managed by the update command.
+const (
+	bumpSectionStart = "<!-- ci-utility:bump -->"
+	bumpSectionEnd   = "<!-- /ci-utility:bump -->"
+)
+
+// executeUpdateGithubPRMeta updates the metadata (title, and optionally body and labels) of a
+// GitHub PR based on its commit messages.
 func executeUpdateGithubPRMeta(ctx context.Context, option *PRUpdateOptions, args []string) error {
 	// Check for required arguments.
 	if len(args) < 1 {
@@ -32,147 +45,145 @@ func executeUpdateGithubPRMeta(ctx context.Context, option *PRUpdateOptions, arg
 	if option.PRNumber == "" {
 		return fmt.Errorf("pull request number is required")
 	}
+	number, err := strconv.Atoi(strings.TrimPrefix(option.PRNumber, "#"))
+	if err != nil || number <= 0 {
+		return fmt.Errorf("invalid pull request number %q", option.PRNumber)
+	}
 
-	// Read GitHub token and repository from environment.
-	token := os.Getenv("GITHUB_TOKEN")
-	repo := os.Getenv("GITHUB_REPOSITORY") // e.g., "owner/repo"
+	// Create a GitHub API client from the token and repository in the environment.
+	client, err := newClientFromEnv()
+	if err != nil {
+		return err
+	}
 
-	// Validate required environment variables.
-	if token == "" || repo == "" {
-		return fmt.Errorf("GITHUB_TOKEN and GITHUB_REPOSITORY environment variables are required")
+	// Fetch the PR and all of its commits.
+	pr, err := client.GetPullRequest(ctx, number)
+	if err != nil {
+		return fmt.Errorf("error fetching PR %d: %w", number, err)
+	}
+	commits, err := client.ListPullRequestCommits(ctx, pr)
+	if err != nil {
+		return fmt.Errorf("error fetching commits of PR %d: %w", number, err)
+	}
+	commitMessages := make([]string, 0, len(commits))
+	for _, commit := range commits {
+		commitMessages = append(commitMessages, commit.Commit.Message)
 	}
 
-	// Fetch commit messages for the PR.
-	commitMessages := getCommitMessages(option.PRNumber, token, repo)
+	// Load the bump rules.
+	bumps, err := semantic.LoadBumps(git.ConfigFile(option.BumpRules))
+	if err != nil {
+		return err
+	}
 
 	// Determine the semantic version bump from commit messages.
-	bump, reason, err := semantic.Bumps.GetVersionBump(commitMessages)
+	bump, reason, err := bumps.GetVersionBump(commitMessages)
 	if err != nil {
 		return fmt.Errorf("error determining bump : %v", err)
 	}
+	slog.InfoContext(ctx, "Determined PR bump", "pr", number, "commits", len(commits), "bump", bump, "reason", reason)
 
-	slog.Debug("Found commit which needs version increament", "level", bump, "message", reason)
-
-	// Get the current PR title.
-	prTitle, err := getPullRequestTitle(ctx, option.PRNumber, token, repo)
-	if err != nil {
-		return fmt.Errorf("error fetching PR title: %v", err)
+	update := UpdatePullRequestRequest{}
+	if bump == semantic.BumpNone {
+		slog.InfoContext(ctx, "No releasable changes in PR", "pr", number)
+	} else if strings.Contains(pr.Title, bump) {
+		// Check if the bump is already present in the title.
+		slog.InfoContext(ctx, "PR title already contains the bump", "pr", number, "bump", bump)
+	} else {
+		// Compose the new PR title.
+		update.Title = fmt.Sprintf("%s: update based on commits", bump)
 	}
-	// Check if the bump is already present in the title.
-	if strings.Contains(prTitle, bump) {
-		slog.InfoContext(ctx, "PR title already contains the bump", "pr", option.PRNumber, "bump", bump)
-		return nil
+	if option.Body {
+		if body := withBumpSection(pr.Body, bump, reason); body != pr.Body {
+			update.Body = body
+		}
+	}
+	var addLabel string
+	var removeLabels []string
+	if option.Label {
+		addLabel, removeLabels = bumpLabelChanges(pr.Labels, option.LabelPrefix, bump)
 	}
-
-	// Compose the new PR title.
-	newTitle := fmt.Sprintf("%s: update based on commits", bump)
 
 	// If dry run, log and exit.
 	if option.DryRun {
-		slog.WarnContext(ctx, "--dry-run", "pr", option.PRNumber, "title", newTitle)
+		slog.WarnContext(ctx, "--dry-run", "pr", number, "title", update.Title, "body", update.Body, "add_label", addLabel, "remove_labels", removeLabels)
 		return nil
 	}
 
-	// Update the PR title via GitHub API.
-	return updatePullRequest(ctx, option.PRNumber, token, repo, newTitle)
-}
-
-// getCommitMessages fetches commit messages for a given PR from GitHub.
-func getCommitMessages(prNumber, token, repo string) []string {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/pulls/%s/commits", repo, prNumber)
-
-	// Prepare the HTTP request.
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Accept", "application/vnd.github+json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	// Check for errors in the HTTP response.
-	if err != nil || resp.StatusCode != 200 {
-		log.Fatalf("Failed to fetch commits: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Parse the JSON response.
-	var result []struct {
-		Message string `json:"commit.message"`
+	// Update the PR via the GitHub API.
+	if update.Title != "" || update.Body != "" {
+		if _, err := client.UpdatePullRequest(ctx, number, update); err != nil {
+			return fmt.Errorf("error updating PR %d: %w", number, err)
+		}
+		slog.InfoContext(ctx, "Updated PR", "pr", number, "title", update.Title, "body_updated", update.Body != "")
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		log.Fatalf("Decode error: %v", err)
+	for _, label := range removeLabels {
+		if err := client.RemoveLabel(ctx, number, label); err != nil {
+			return fmt.Errorf("error removing label %s from PR %d: %w", label, number, err)
+		}
+		slog.InfoContext(ctx, "Removed PR label", "pr", number, "label", label)
 	}
-
-	// Extract commit messages.
-	var messages []string
-	for _, c := range result {
-		messages = append(messages, c.Message)
+	if addLabel != "" {
+		if err := client.AddLabels(ctx, number, addLabel); err != nil {
+			return fmt.Errorf("error adding label %s to PR %d: %w", addLabel, number, err)
+		}
+		slog.InfoContext(ctx, "Added PR label", "pr", number, "label", addLabel)
 	}
-	return messages
+	return nil
 }
 
-// updatePullRequest updates the title of a GitHub PR.
-func updatePullRequest(ctx context.Context, prNumber, token, repo, title string) error {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/pulls/%s", repo, prNumber)
-
-	// Prepare the request body.
-	pr := struct {
-		Title string `json:"title"`
-	}{Title: title}
-	data, _ := json.Marshal(pr)
-
-	// Create the HTTP PATCH request.
-	req, _ := http.NewRequest("PATCH", url, strings.NewReader(string(data)))
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	// Check for errors in the HTTP response.
-	if err != nil || resp.StatusCode >= 300 {
-		return fmt.Errorf("failed to update PR: %v", err)
+// withBumpSection returns the PR body with the section describing the bump added, or replaced
+// if the body already has one.
+func withBumpSection(body, bump, reason string) string {
+	description := fmt.Sprintf("**Version bump:** %s", bump)
+	switch {
+	case bump == semantic.BumpNone:
+		description += " (no releasable changes)"
+	case reason != "":
+		description += fmt.Sprintf(" from `%s`", strings.ReplaceAll(reason, "`", "'"))
 	}
-	defer resp.Body.Close()
+	section := bumpSectionStart + "\n" + description + "\n" + bumpSectionEnd
 
-	// Ensure the status code is 200 OK.
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("failed to update PR, status code: %d", resp.StatusCode)
+	start := strings.Index(body, bumpSectionStart)
+	end := strings.Index(body, bumpSectionEnd)
+	if start >= 0 && end > start {
+		return body[:start] + section + body[end+len(bumpSectionEnd):]
 	}
-
-	// Log the successful update.
-	slog.InfoContext(ctx, "Updated PR title", "pr", prNumber, "title", title)
-	return nil
+	if strings.TrimSpace(body) == "" {
+		return section
+	}
+	return strings.TrimRight(body, "\n") + "\n\n" + section
 }
 
-// getPullRequestTitle fetches the title of a GitHub PR.
-func getPullRequestTitle(_ context.Context, prNumber, token, repo string) (string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/pulls/%s", repo, prNumber)
-
-	// Prepare the HTTP request.
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Accept", "application/vnd.github+json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	// Check for errors in the HTTP response.
-	if err != nil || resp.StatusCode != 200 {
-		return "", fmt.Errorf("failed to fetch PR title: %v", err)
-	}
-	defer resp.Body.Close()
-	// Ensure the status code is 200 OK.
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("failed to fetch PR title, status code: %d", resp.StatusCode)
-	}
+// bumpLabelChanges returns the bump label to add to a PR, if it is not already set, and the
+// labels for other bumps to remove. No label is added for a bump of none.
+func bumpLabelChanges(labels []Label, prefix, bump string) (string, []string) {
+	want := ""
+	if bump != semantic.BumpNone {
+		want = prefix + bump
+	}
+	add := want
+	remove := []string{}
+	for _, label := range labels {
+		switch {
+		case label.Name == want:
+			add = ""
+		case isBumpLabel(label.Name, prefix):
+			remove = append(remove, label.Name)
+		}
+	}
+	return add, remove
+}
 
-	// Parse the JSON response.
-	var result struct {
-		Title string `json:"title"`
+// isBumpLabel returns true if a label is the prefix followed by a bump level, eg bump:patch.
+func isBumpLabel(name, prefix string) bool {
+	level, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return false
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("decode error: %v", err)
+	switch level {
+	case semantic.BumpNone, "patch", "minor", "major":
+		return true
 	}
-
-	// Return the PR title.
-	return result.Title, nil
+	return false
 }
@@ -3,10 +3,14 @@ package github
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
-	"net/http"
 	"os"
 	"strings"
+
+	"github.com/davidjspooner/ci-utility/internal/git"
+	"github.com/davidjspooner/ci-utility/internal/template"
+	"github.com/davidjspooner/ci-utility/pkg/semantic"
 )
 
 // ReleaseCreateOptions holds the options for creating a GitHub release.
@@ -17,9 +21,27 @@ type ReleaseCreateOptions struct {
 	Body       string `flag:"--body,Description of the release"`
 	Draft      bool   `flag:"--draft,Create the release as a draft"`
 	Prerelease bool   `flag:"--prerelease,Mark the release as a prerelease"`
+	IfExists   string `flag:"--if-exists,If the release already exists: fail, update its name and body (and draft or prerelease flags if given), or skip (leave it as it is); assets are uploaded either way"`
+	Update     bool   `flag:"--update,Same as --if-exists=update"`
+
+	Publish      bool `flag:"--publish,Publish an existing draft release when updating it"`
+	NoPrerelease bool `flag:"--no-prerelease,Mark an existing prerelease as a full release when updating it"`
+
+	BodyFile       string `flag:"--body-file,Read the description from a file, or - for stdin"`
+	BodyTemplate   string `flag:"--body-template,Template file for the description, with the values TAG NAME PREVIOUS_TAG REPOSITORY and CHANGELOG"`
+	TemplateFormat string `flag:"--template-format,Type of the body template (go/text or markdown)"`
+	Changelog      bool   `flag:"--changelog,Add a changelog of the conventional commits since the previous tag to the description"`
+	PreviousTag    string `flag:"--previous-tag,Tag the changelog and generated notes start from (defaults to the previous version tag with the same prefix, skipping pre-releases for a release)"`
+	GenerateNotes  bool   `flag:"--generate-notes,Add the release notes generated by GitHub from merged pull requests after the description"`
+
+	Parallel      int `flag:"--parallel,Number of assets to upload at the same time"`
+	UploadRetries int `flag:"--upload-retries,Times to retry an asset upload which fails or does not match the local file"`
 }
 
 // executeGithubReleaseCreate creates a GitHub release and uploads assets.
+// If the release already exists it can be updated instead, so that a failed job can be re-run,
+// and assets which were already uploaded are skipped or replaced. Assets are uploaded in parallel
+// and each one is checked against the local file once it has been uploaded.
 func executeGithubReleaseCreate(ctx context.Context, option *ReleaseCreateOptions, args []string) error {
 	// Validate the required options.
 	files, err := globFiles(args)
@@ -29,22 +51,31 @@ func executeGithubReleaseCreate(ctx context.Context, option *ReleaseCreateOption
 	if len(files) == 0 {
 		return fmt.Errorf("no files found matching the pattern")
 	}
-
-	// get the GitHub token and repository from environment variables.
-	token := os.Getenv("GITHUB_TOKEN")
-	repo := os.Getenv("GITHUB_REPOSITORY") // e.g., "owner/repo"
-
-	if token == "" || repo == "" {
-		return fmt.Errorf("GITHUB_TOKEN and GITHUB_REPOSITORY environment variables are required")
+	if option.Parallel < 1 {
+		return fmt.Errorf("--parallel must be at least 1")
+	}
+	if option.UploadRetries < 0 {
+		return fmt.Errorf("--upload-retries must not be negative")
+	}
+	if option.Update {
+		option.IfExists = "update"
+	}
+	switch option.IfExists {
+	case "fail", "update", "skip":
+	default:
+		return fmt.Errorf("unknown --if-exists %q, expected fail, update or skip", option.IfExists)
+	}
+	if option.Draft && option.Publish {
+		return fmt.Errorf("--draft and --publish cannot be used together")
+	}
+	if option.Prerelease && option.NoPrerelease {
+		return fmt.Errorf("--prerelease and --no-prerelease cannot be used together")
 	}
 
-	// Create a GitHub API client with the provided token and repository information.
-	client := Client{
-		HTTPClient: http.DefaultClient,
-		BaseURL:    "https://api.github.com",
-		Token:      token,
-		Owner:      strings.Split(repo, "/")[0],
-		Repo:       strings.SplitN(repo, "/", 2)[1],
+	// Create a GitHub API client from the token and repository in the environment.
+	client, err := newClientFromEnv()
+	if err != nil {
+		return err
 	}
 
 	if option.TagName == "" {
@@ -55,34 +86,191 @@ func executeGithubReleaseCreate(ctx context.Context, option *ReleaseCreateOption
 	}
 
 	// prepare the release request payload.
+	body, err := releaseBody(ctx, option, client.Owner+"/"+client.Repo)
+	if err != nil {
+		return err
+	}
 	releaseReq := CreateReleaseRequest{
 		TagName:    option.TagName,
 		Name:       option.Name,
-		Body:       option.Body,
+		Body:       body,
 		Draft:      option.Draft,
 		Prerelease: option.Prerelease,
 	}
+	if option.GenerateNotes {
+		// GitHub picks the previous release itself when it generates notes for a new release.
+		releaseReq.GenerateReleaseNotes = true
+		if option.PreviousTag != "" {
+			if releaseReq, err = withGeneratedNotes(ctx, client, releaseReq, option.PreviousTag); err != nil {
+				return err
+			}
+		}
+	}
 
-	// Create the release using the GitHub API.
-	var release ReleaseResponse
-	err = client.PostJSON(ctx, "/repos/{owner}/{repo}/releases", releaseReq, &release)
+	// Create the release, or update or reuse an existing one.
+	// The flags of an existing release are only changed if they are given.
+	flags := UpdateReleaseRequest{
+		Draft:      optionalFlag(option.Draft, option.Publish),
+		Prerelease: optionalFlag(option.Prerelease, option.NoPrerelease),
+	}
+	release, err := createOrUpdateRelease(ctx, client, releaseReq, flags, option.IfExists)
 	if err != nil {
-		slog.ErrorContext(ctx, "Failed to create release", "tag", option.TagName, "error", err)
-		os.Exit(1)
+		return err
+	}
+
+	// Upload each file as an asset to the release.
+	return uploadReleaseAssets(ctx, client, release, files, option.Parallel, option.UploadRetries)
+}
+
+// createOrUpdateRelease creates a release, or if one exists for the tag fails, updates it or
+// returns it unchanged depending on ifExists. An existing release gets the draft and prerelease
+// flags which are set in flags, and keeps the others.
+func createOrUpdateRelease(ctx context.Context, client *Client, releaseReq CreateReleaseRequest, flags UpdateReleaseRequest, ifExists string) (*ReleaseResponse, error) {
+	release, err := client.GetReleaseByTag(ctx, releaseReq.TagName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the release for tag %s: %w", releaseReq.TagName, err)
+	}
+
+	switch {
+	case release == nil:
+		release, err = client.CreateRelease(ctx, releaseReq)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create release %s: %w", releaseReq.TagName, err)
+		}
+		slog.InfoContext(ctx, "Created release", "id", release.ID, "tag", releaseReq.TagName, "name", release.Name, "url", release.URL)
+	case ifExists == "update":
+		// Notes can only be generated by the API when a release is created.
+		if releaseReq.GenerateReleaseNotes {
+			if releaseReq, err = withGeneratedNotes(ctx, client, releaseReq, ""); err != nil {
+				return nil, err
+			}
+		}
+		update := UpdateReleaseRequest{
+			Name:       releaseReq.Name,
+			Body:       releaseReq.Body,
+			Draft:      flags.Draft,
+			Prerelease: flags.Prerelease,
+		}
+		release, err = client.UpdateRelease(ctx, release.ID, update)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update release %s: %w", releaseReq.TagName, err)
+		}
+		slog.InfoContext(ctx, "Updated release", "id", release.ID, "tag", releaseReq.TagName, "name", release.Name, "url", release.URL)
+	case ifExists == "skip":
+		slog.InfoContext(ctx, "Release already exists, leaving it unchanged", "id", release.ID, "tag", releaseReq.TagName, "url", release.URL)
+	default:
+		return nil, fmt.Errorf("release for tag %s already exists at %s (use --if-exists=update or skip to reuse it)", releaseReq.TagName, release.URL)
+	}
+	return release, nil
+}
+
+// optionalFlag returns a pointer to true if set is given, to false if clear is given, or nil to
+// leave the flag as it is.
+func optionalFlag(set, clear bool) *bool {
+	if !set && !clear {
+		return nil
 	}
+	return &set
+}
 
-	slog.InfoContext(ctx, "Created release", "id", release.ID, "tag", option.TagName, "name", release.Name, "url", release.URL)
+// releaseBody returns the description of the release from --body, --body-file or --body-template.
+// With --changelog the changelog of the conventional commits since the previous tag is added after
+// the description, or passed to the template as CHANGELOG.
+func releaseBody(ctx context.Context, option *ReleaseCreateOptions, repository string) (string, error) {
+	sources := 0
+	for _, set := range []bool{option.Body != "", option.BodyFile != "", option.BodyTemplate != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return "", fmt.Errorf("only one of --body, --body-file and --body-template can be used")
+	}
 
-	// Upload each file as an asset to the created release.
-	for _, path := range files {
-		var asset AssetResponse
-		err = client.UploadBinaryFile(ctx, release.ID, path, &asset)
+	// Generate the changelog from the commits between the tags.
+	changelog := ""
+	previousTag := option.PreviousTag
+	if option.Changelog {
+		// Start from a tag of the same kind, eg a component release starts from the previous
+		// release of that component.
+		tagPrefix := "v"
+		if prefix, _, _, err := semantic.ExtractVersionFromTag(option.TagName); err == nil {
+			tagPrefix = prefix
+		}
+		log, err := git.BuildChangelog(ctx, option.PreviousTag, option.TagName, tagPrefix, false)
 		if err != nil {
-			slog.ErrorContext(ctx, "Failed to upload asset", "name", path, "error", err)
-			os.Exit(1)
+			return "", fmt.Errorf("failed to generate the changelog for %s: %w", option.TagName, err)
 		}
-		slog.InfoContext(ctx, "Uploaded asset", "name", asset.Name, "url", asset.URL)
+		changelog = log.Markdown()
+		previousTag = log.From
 	}
 
-	return nil
+	switch {
+	case option.BodyFile != "":
+		body, err := readBodyFile(option.BodyFile)
+		if err != nil {
+			return "", err
+		}
+		return joinNotes(body, changelog), nil
+	case option.BodyTemplate != "":
+		content, err := os.ReadFile(option.BodyTemplate)
+		if err != nil {
+			return "", fmt.Errorf("failed to read template %s: %w", option.BodyTemplate, err)
+		}
+		values := template.NewValues(map[string]string{
+			"TAG":          option.TagName,
+			"NAME":         option.Name,
+			"PREVIOUS_TAG": previousTag,
+			"REPOSITORY":   repository,
+			"CHANGELOG":    changelog,
+		})
+		body, err := template.ExpandString(string(content), option.TemplateFormat, values)
+		if err != nil {
+			return "", fmt.Errorf("failed to expand template %s: %w", option.BodyTemplate, err)
+		}
+		return body, nil
+	default:
+		return joinNotes(option.Body, changelog), nil
+	}
+}
+
+// readBodyFile reads a release description from a file, or from stdin if path is "-".
+func readBodyFile(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the release description from %s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+// withGeneratedNotes adds the release notes generated by GitHub after the body of a release
+// request, for when the generate_release_notes flag of the create API cannot be used.
+func withGeneratedNotes(ctx context.Context, client *Client, releaseReq CreateReleaseRequest, previousTag string) (CreateReleaseRequest, error) {
+	notes, err := client.GenerateReleaseNotes(ctx, releaseReq.TagName, previousTag)
+	if err != nil {
+		return releaseReq, fmt.Errorf("failed to generate release notes for %s: %w", releaseReq.TagName, err)
+	}
+	releaseReq.Body = joinNotes(releaseReq.Body, notes.Body)
+	releaseReq.GenerateReleaseNotes = false
+	return releaseReq, nil
+}
+
+// joinNotes joins the non-empty parts of a release description with blank lines.
+func joinNotes(parts ...string) string {
+	notes := []string{}
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			notes = append(notes, part)
+		}
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return strings.Join(notes, "\n\n") + "\n"
 }
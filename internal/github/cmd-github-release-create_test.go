@@ -0,0 +1,42 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestCreateOrUpdateReleaseFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		option   ReleaseCreateOptions
+		wantBody string
+	}{
+		{name: "flags kept", wantBody: `{"name":"v1.0.0","body":"notes"}`},
+		{name: "draft", option: ReleaseCreateOptions{Draft: true}, wantBody: `{"name":"v1.0.0","body":"notes","draft":true}`},
+		{name: "publish", option: ReleaseCreateOptions{Publish: true}, wantBody: `{"name":"v1.0.0","body":"notes","draft":false}`},
+		{name: "prerelease", option: ReleaseCreateOptions{Prerelease: true}, wantBody: `{"name":"v1.0.0","body":"notes","prerelease":true}`},
+		{name: "no prerelease", option: ReleaseCreateOptions{NoPrerelease: true}, wantBody: `{"name":"v1.0.0","body":"notes","prerelease":false}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, client := newTestServer(t,
+				reply(http.StatusOK, `{"id":7,"tag_name":"v1.0.0","draft":true,"prerelease":true}`),
+				reply(http.StatusOK, `{"id":7,"tag_name":"v1.0.0"}`),
+			)
+			client.BaseURL = s.URL
+			releaseReq := CreateReleaseRequest{TagName: "v1.0.0", Name: "v1.0.0", Body: "notes"}
+			flags := UpdateReleaseRequest{
+				Draft:      optionalFlag(tt.option.Draft, tt.option.Publish),
+				Prerelease: optionalFlag(tt.option.Prerelease, tt.option.NoPrerelease),
+			}
+			if _, err := createOrUpdateRelease(context.Background(), client, releaseReq, flags, "update"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := "PATCH /repos/owner/repo/releases/7 " + tt.wantBody
+			if len(s.requests) != 2 || s.requests[1] != want {
+				t.Errorf("expected %q, got %q", want, s.requests)
+			}
+		})
+	}
+}
@@ -15,14 +15,21 @@ func AddCommandsTo(parent cmd.Command) error {
 		"create",
 		"Create a GitHub release",
 		executeGithubReleaseCreate,
-		&ReleaseCreateOptions{},
+		&ReleaseCreateOptions{
+			IfExists:       "fail",
+			TemplateFormat: "go/text",
+			Parallel:       4,
+			UploadRetries:  2,
+		},
 	)
 	// Create the PR update command.
 	prUpdate := cmd.NewCommand(
 		"update",
 		"Update a GitHub pull request with the latest changes from the base branch",
 		executeUpdateGithubPRMeta,
-		&PRUpdateOptions{},
+		&PRUpdateOptions{
+			LabelPrefix: "bump:",
+		},
 	)
 
 	// Create command groups for pull requests and releases.
@@ -0,0 +1,255 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressMinSize is the smallest upload whose progress is logged as it is sent.
+const progressMinSize = 8 << 20
+
+// assetUpload is a local file to upload as a release asset.
+type assetUpload struct {
+	Path   string
+	Name   string
+	Size   int64
+	Digest string // eg "sha256:<hex>"
+}
+
+// newAssetUploads returns the uploads for files, with their sizes and digests. Files must have
+// different names, as the name of an asset is the base name of its file.
+func newAssetUploads(files []string) ([]assetUpload, error) {
+	uploads := make([]assetUpload, 0, len(files))
+	paths := map[string]string{}
+	for _, path := range files {
+		name := filepath.Base(path)
+		if other, ok := paths[name]; ok {
+			return nil, fmt.Errorf("%s and %s would both be uploaded as %s", other, path, name)
+		}
+		paths[name] = path
+		size, digest, err := fileDigest(path)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, assetUpload{Path: path, Name: name, Size: size, Digest: digest})
+	}
+	return uploads, nil
+}
+
+// uploadReleaseAssets uploads files to a release, up to parallel at a time. Assets which are
+// already attached with the same size and digest are skipped, and other assets with the same
+// name are replaced. Each upload is verified and retried up to retries times, and every file
+// is attempted even if some fail.
+func uploadReleaseAssets(ctx context.Context, client *Client, release *ReleaseResponse, files []string, parallel, retries int) error {
+	uploads, err := newAssetUploads(files)
+	if err != nil {
+		return err
+	}
+	existing, err := client.ListReleaseAssets(ctx, release.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list the assets of release %s: %w", release.TagName, err)
+	}
+
+	// Upload with a pool of workers.
+	start := time.Now()
+	var sent atomic.Int64
+	errs := make([]error, len(uploads))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for range max(min(parallel, len(uploads)), 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				n, err := uploadAsset(ctx, client, release, existing, uploads[i], retries)
+				sent.Add(n)
+				errs[i] = err
+			}
+		}()
+	}
+	for i := range uploads {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	elapsed := time.Since(start)
+	slog.InfoContext(ctx, "Uploaded assets",
+		"assets", len(uploads)-failed,
+		"failed", failed,
+		"bytes", sent.Load(),
+		"duration", elapsed.Round(time.Millisecond),
+		"throughput", formatThroughput(sent.Load(), elapsed),
+	)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d asset(s) failed to upload: %w", failed, len(uploads), errors.Join(errs...))
+	}
+	return nil
+}
+
+// uploadAsset uploads one file to a release, retrying failed or incomplete uploads, and returns
+// the number of bytes sent by the upload which succeeded.
+func uploadAsset(ctx context.Context, client *Client, release *ReleaseResponse, existing []AssetResponse, upload assetUpload, retries int) (int64, error) {
+	// Skip an identical asset, or remove one which differs.
+	for _, asset := range existing {
+		if asset.Name != upload.Name {
+			continue
+		}
+		if asset.State == "uploaded" && asset.Size == upload.Size && asset.Digest == upload.Digest {
+			slog.InfoContext(ctx, "Asset already uploaded", "name", upload.Name, "size", upload.Size, "digest", upload.Digest)
+			return 0, nil
+		}
+		slog.InfoContext(ctx, "Replacing asset", "name", upload.Name, "state", asset.State, "size", asset.Size, "digest", asset.Digest, "new_size", upload.Size, "new_digest", upload.Digest)
+		if err := client.DeleteReleaseAsset(ctx, asset.ID); err != nil {
+			return 0, fmt.Errorf("failed to delete asset %s: %w", upload.Name, err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		asset, err := uploadAssetOnce(ctx, client, release, upload)
+		if err == nil {
+			err = verifyAsset(asset, upload)
+		}
+		if err == nil {
+			elapsed := time.Since(start)
+			slog.InfoContext(ctx, "Uploaded asset",
+				"name", asset.Name,
+				"size", asset.Size,
+				"duration", elapsed.Round(time.Millisecond),
+				"throughput", formatThroughput(upload.Size, elapsed),
+				"url", asset.URL,
+			)
+			return upload.Size, nil
+		}
+		if attempt >= retries || ctx.Err() != nil {
+			return 0, err
+		}
+
+		wait := backoff(attempt)
+		slog.WarnContext(ctx, "Retrying asset upload", "name", upload.Name, "attempt", attempt+1, "wait", wait.Round(time.Second), "error", err)
+		if err := sleep(ctx, wait); err != nil {
+			return 0, err
+		}
+		// A failed upload can leave a partial asset behind, which would make the next one fail.
+		if err := removeAsset(ctx, client, release.ID, upload.Name); err != nil {
+			slog.WarnContext(ctx, "Failed to remove partial asset", "name", upload.Name, "error", err)
+		}
+	}
+}
+
+// uploadAssetOnce sends a file to the upload URL of a release, logging the progress of large files.
+func uploadAssetOnce(ctx context.Context, client *Client, release *ReleaseResponse, upload assetUpload) (*AssetResponse, error) {
+	file, err := os.Open(upload.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", upload.Path, err)
+	}
+	defer file.Close()
+
+	meta := UploadMeta{
+		Name: upload.Name,
+		// Remove the URI template, eg "{?name,label}", from the upload URL.
+		UploadURL: strings.SplitN(release.UploadURL, "{", 2)[0],
+	}
+	var data io.Reader = file
+	if upload.Size >= progressMinSize {
+		data = &progressReader{ctx: ctx, reader: file, name: upload.Name, size: upload.Size, start: time.Now()}
+	}
+	var asset AssetResponse
+	if err := client.UploadBinaryStream(ctx, release.ID, meta, data, upload.Size, &asset); err != nil {
+		return nil, fmt.Errorf("failed to upload asset %s: %w", upload.Path, err)
+	}
+	return &asset, nil
+}
+
+// verifyAsset returns an error if an uploaded asset is not complete, or does not have the size
+// or digest of the local file. Assets without a digest are checked by size.
+func verifyAsset(asset *AssetResponse, upload assetUpload) error {
+	switch {
+	case asset.State != "" && asset.State != "uploaded":
+		return fmt.Errorf("asset %s is %s after uploading", upload.Name, asset.State)
+	case asset.Size != upload.Size:
+		return fmt.Errorf("asset %s was uploaded with %d bytes, expected %d", upload.Name, asset.Size, upload.Size)
+	case asset.Digest != "" && asset.Digest != upload.Digest:
+		return fmt.Errorf("asset %s was uploaded with digest %s, expected %s", upload.Name, asset.Digest, upload.Digest)
+	}
+	return nil
+}
+
+// removeAsset deletes any asset of a release with the given name.
+func removeAsset(ctx context.Context, client *Client, releaseID int64, name string) error {
+	assets, err := client.ListReleaseAssets(ctx, releaseID)
+	if err != nil {
+		return err
+	}
+	for _, asset := range assets {
+		if asset.Name != name {
+			continue
+		}
+		slog.InfoContext(ctx, "Removing asset", "name", name, "state", asset.State, "size", asset.Size)
+		if err := client.DeleteReleaseAsset(ctx, asset.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// progressReader logs the progress of an upload at each quarter of its size. Seeking back for a
+// retry starts the progress again.
+type progressReader struct {
+	ctx      context.Context
+	reader   io.ReadSeeker
+	name     string
+	size     int64
+	read     int64
+	quarters int64 // quarters of the progress which have been logged
+	start    time.Time
+}
+
+// Read reads from the underlying reader and logs the progress.
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.read += int64(n)
+	for p.quarters < 3 && p.read*4 >= (p.quarters+1)*p.size {
+		p.quarters++
+		slog.InfoContext(p.ctx, "Uploading asset",
+			"name", p.name,
+			"percent", p.quarters*25,
+			"bytes", p.read,
+			"throughput", formatThroughput(p.read, time.Since(p.start)),
+		)
+	}
+	return n, err
+}
+
+// Seek seeks the underlying reader and restarts the progress.
+func (p *progressReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := p.reader.Seek(offset, whence)
+	p.read = pos
+	p.quarters = p.read * 4 / p.size
+	p.start = time.Now()
+	return pos, err
+}
+
+// formatThroughput formats a transfer rate in MiB/s.
+func formatThroughput(bytes int64, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f MiB/s", float64(bytes)/(1<<20)/elapsed.Seconds())
+}
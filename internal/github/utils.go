@@ -1,7 +1,14 @@
 package github
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
 	"path/filepath"
+	"strings"
 )
 
 // globFiles expands a list of glob patterns into a slice of matching file paths.
@@ -18,3 +25,44 @@ func globFiles(patterns []string) ([]string, error) {
 	// Return the collected files.
 	return files, nil
 }
+
+// newClientFromEnv creates a Client for the repository in GITHUB_REPOSITORY (eg "owner/repo")
+// authenticated with GITHUB_TOKEN. GITHUB_API_URL is used for GitHub Enterprise Server if set.
+func newClientFromEnv() (*Client, error) {
+	token := os.Getenv("GITHUB_TOKEN")
+	repo := os.Getenv("GITHUB_REPOSITORY")
+	if token == "" || repo == "" {
+		return nil, fmt.Errorf("GITHUB_TOKEN and GITHUB_REPOSITORY environment variables are required")
+	}
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" {
+		return nil, fmt.Errorf("GITHUB_REPOSITORY must be owner/repo, got %q", repo)
+	}
+	baseURL := strings.TrimSuffix(os.Getenv("GITHUB_API_URL"), "/")
+	if baseURL == "" {
+		baseURL = "https://api.github.com"
+	}
+	return &Client{
+		HTTPClient: http.DefaultClient,
+		BaseURL:    baseURL,
+		Token:      token,
+		Owner:      owner,
+		Repo:       name,
+	}, nil
+}
+
+// fileDigest returns the size of a file and its digest in the form GitHub reports for release
+// assets, eg "sha256:<hex>".
+func fileDigest(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return size, "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
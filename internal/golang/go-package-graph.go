@@ -0,0 +1,312 @@
+package golang
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// AffectedPackage is a package with changed files, or which imports one, directly or indirectly.
+type AffectedPackage struct {
+	Dir        string   `json:"dir"`             // directory relative to the root, eg "./pkg/semantic"
+	ImportPath string   `json:"import_path"`     // eg "github.com/owner/repo/pkg/semantic"
+	Files      []string `json:"files,omitempty"` // changed files which belong to the package
+	Via        string   `json:"via,omitempty"`   // import path of the affected package it imports, if it has no changed files
+}
+
+// goModule is a go.mod file found under the root.
+type goModule struct {
+	Dir  string // directory relative to the root, "." for the root itself
+	Path string // module path
+}
+
+// goPackageNode is a package in a goPackageGraph.
+type goPackageNode struct {
+	Dir         string
+	ImportPath  string
+	ModuleDir   string
+	Imports     []string // import paths of the package
+	TestImports []string // import paths only imported by the tests of the package
+}
+
+// goPackageGraph is the import graph of the packages in the modules under a root directory.
+type goPackageGraph struct {
+	modules        []goModule                  // longest directory first, so the first match is the innermost module
+	packages       map[string]*goPackageNode   // by directory relative to the root
+	dependents     map[string][]*goPackageNode // packages by the import paths they import
+	testDependents map[string][]*goPackageNode // packages by the import paths only their tests import
+}
+
+// AffectedPackages maps files changed under root (given relative to root) to the packages they
+// belong to, and adds every package which imports one of those, directly or indirectly, through
+// its own code or its tests. Only changes to the code of a package, not to its tests, affect the
+// packages which import it. A change to go.mod or go.sum affects every package in the module, and
+// other files belong to the package in the nearest directory above them. Packages are sorted by
+// directory.
+func AffectedPackages(root string, files []string) ([]AffectedPackage, error) {
+	graph, err := newGoPackageGraph(root)
+	if err != nil {
+		return nil, err
+	}
+	return graph.affected(files), nil
+}
+
+// newGoPackageGraph builds the import graph of the packages in the package inventory of root.
+func newGoPackageGraph(root string) (*goPackageGraph, error) {
+	graph := &goPackageGraph{
+		packages:       map[string]*goPackageNode{},
+		dependents:     map[string][]*goPackageNode{},
+		testDependents: map[string][]*goPackageNode{},
+	}
+	if err := graph.addModules(root); err != nil {
+		return nil, err
+	}
+
+	var inventory goPackageInventory
+	if err := inventory.addPackages(root); err != nil {
+		return nil, fmt.Errorf("failed to find packages in %s: %w", root, err)
+	}
+	for i := range inventory {
+		pi := &inventory[i]
+		dir, err := relativeDir(root, pi.DirPath)
+		if err != nil || isIgnoredDir(dir) || graph.packages[dir] != nil {
+			// Test packages share a directory with the package they test, and all the
+			// files in the directory are scanned at once.
+			continue
+		}
+		module := graph.moduleFor(dir)
+		if module == nil {
+			continue
+		}
+		node := &goPackageNode{Dir: dir, ImportPath: module.Path, ModuleDir: module.Dir}
+		if dir != module.Dir {
+			node.ImportPath = module.Path + "/" + strings.TrimPrefix(dir, module.Dir+"/")
+			if module.Dir == "." {
+				node.ImportPath = module.Path + "/" + dir
+			}
+		}
+
+		// Collect the imports of all the files, keeping those only used by tests apart.
+		testImports := []string{}
+		err = pi.scanFiles(func(pi *goPackageInfo, filename string, fset *token.FileSet, f *ast.File) error {
+			imports := &node.Imports
+			if strings.HasSuffix(filename, "_test.go") {
+				imports = &testImports
+			}
+			for _, spec := range f.Imports {
+				if importPath, err := strconv.Unquote(spec.Path.Value); err == nil && !slices.Contains(*imports, importPath) {
+					*imports = append(*imports, importPath)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the imports of %s: %w", pi.DirPath, err)
+		}
+		for _, importPath := range testImports {
+			if !slices.Contains(node.Imports, importPath) {
+				node.TestImports = append(node.TestImports, importPath)
+			}
+		}
+		graph.packages[dir] = node
+	}
+
+	// Index the packages by the packages they import.
+	for _, node := range graph.packages {
+		for _, importPath := range node.Imports {
+			graph.dependents[importPath] = append(graph.dependents[importPath], node)
+		}
+		for _, importPath := range node.TestImports {
+			graph.testDependents[importPath] = append(graph.testDependents[importPath], node)
+		}
+	}
+	return graph, nil
+}
+
+// addModules finds the go.mod files under root.
+func (graph *goPackageGraph) addModules(root string) error {
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		dir, relErr := relativeDir(root, p)
+		if relErr != nil {
+			return relErr
+		}
+		if d.IsDir() {
+			if dir != "." && isIgnoredDir(dir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "go.mod" {
+			return nil
+		}
+		modulePath, err := readModulePath(p)
+		if err != nil {
+			return err
+		}
+		graph.modules = append(graph.modules, goModule{Dir: path.Dir(dir), Path: modulePath})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find go modules in %s: %w", root, err)
+	}
+	if len(graph.modules) == 0 {
+		return fmt.Errorf("no go.mod found in %s", root)
+	}
+	slices.SortFunc(graph.modules, func(a, b goModule) int { return len(b.Dir) - len(a.Dir) })
+	return nil
+}
+
+// moduleFor returns the innermost module containing a directory, or nil if there is none.
+func (graph *goPackageGraph) moduleFor(dir string) *goModule {
+	for i, module := range graph.modules {
+		if module.Dir == "." || dir == module.Dir || strings.HasPrefix(dir, module.Dir+"/") {
+			return &graph.modules[i]
+		}
+	}
+	return nil
+}
+
+// packageFor returns the package in the nearest directory above a file, without leaving its module.
+func (graph *goPackageGraph) packageFor(file string) *goPackageNode {
+	dir := path.Dir(file)
+	for {
+		if node := graph.packages[dir]; node != nil {
+			return node
+		}
+		if dir == "." || slices.ContainsFunc(graph.modules, func(m goModule) bool { return m.Dir == dir }) {
+			return nil
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// affected returns the packages the changed files belong to and the packages which import them.
+func (graph *goPackageGraph) affected(files []string) []AffectedPackage {
+	result := map[string]*AffectedPackage{}
+	queued := map[string]bool{}
+	queue := []*goPackageNode{}
+	// mark adds a package to the result, and queues it to add its dependents if the change
+	// affects the packages which import it.
+	mark := func(node *goPackageNode, file, via string, propagate bool) {
+		affected := result[node.Dir]
+		if affected == nil {
+			affected = &AffectedPackage{Dir: displayDir(node.Dir), ImportPath: node.ImportPath, Via: via}
+			result[node.Dir] = affected
+		}
+		if file != "" {
+			affected.Files = append(affected.Files, file)
+			affected.Via = ""
+		}
+		if propagate && !queued[node.Dir] {
+			queued[node.Dir] = true
+			queue = append(queue, node)
+		}
+	}
+
+	for _, file := range files {
+		file = path.Clean(filepath.ToSlash(file))
+		switch path.Base(file) {
+		case "go.mod", "go.sum":
+			// Dependency changes can affect every package in the module.
+			for _, node := range graph.packages {
+				if node.ModuleDir == path.Dir(file) {
+					mark(node, file, "", true)
+				}
+			}
+			continue
+		case "go.work", "go.work.sum":
+			for _, node := range graph.packages {
+				mark(node, file, "", true)
+			}
+			continue
+		}
+		if node := graph.packageFor(file); node != nil {
+			// Changes to tests do not affect the packages which import the package.
+			mark(node, file, "", !strings.HasSuffix(file, "_test.go"))
+		}
+	}
+
+	// Walk the reverse dependencies of everything that changed.
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, dependent := range graph.dependents[node.ImportPath] {
+			mark(dependent, "", node.ImportPath, true)
+		}
+		// Only the tests of packages which import it in their tests are affected.
+		for _, dependent := range graph.testDependents[node.ImportPath] {
+			mark(dependent, "", node.ImportPath, false)
+		}
+	}
+
+	packages := make([]AffectedPackage, 0, len(result))
+	for _, affected := range result {
+		packages = append(packages, *affected)
+	}
+	slices.SortFunc(packages, func(a, b AffectedPackage) int { return strings.Compare(a.Dir, b.Dir) })
+	return packages
+}
+
+// readModulePath returns the module path declared in a go.mod file.
+func readModulePath(goMod string) (string, error) {
+	f, err := os.Open(goMod)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if rest, ok := strings.CutPrefix(line, "module"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			modulePath, _, _ := strings.Cut(strings.TrimSpace(rest), "//")
+			modulePath = strings.TrimSpace(modulePath)
+			if unquoted, err := strconv.Unquote(modulePath); err == nil {
+				modulePath = unquoted
+			}
+			return modulePath, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no module directive in %s", goMod)
+}
+
+// relativeDir returns a path relative to root using forward slashes.
+func relativeDir(root, p string) (string, error) {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// isIgnoredDir returns true for directories the go command ignores: vendor, testdata, and
+// directories starting with "." or "_".
+func isIgnoredDir(dir string) bool {
+	for _, part := range strings.Split(dir, "/") {
+		if part == "vendor" || part == "testdata" || (part != "." && (strings.HasPrefix(part, ".") || strings.HasPrefix(part, "_"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// displayDir returns a directory relative to the root in the form the go command accepts, eg "./pkg/semantic".
+func displayDir(dir string) string {
+	if dir == "." {
+		return dir
+	}
+	return "./" + dir
+}
@@ -0,0 +1,101 @@
+package golang
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAffectedPackages(t *testing.T) {
+	// testdata/graph has a root module, where . imports pkg/api which imports pkg/util, and the
+	// tests of pkg/util import internal/testhelp. tools/linter is a nested module.
+	graph, err := newGoPackageGraph("testdata/graph")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{
+			name:  "transitive dependents",
+			files: []string{"pkg/util/util.go"},
+			want:  []string{".", "./pkg/api", "./pkg/util"},
+		},
+		{
+			name:  "test only change",
+			files: []string{"pkg/util/util_test.go"},
+			want:  []string{"./pkg/util"},
+		},
+		{
+			name:  "package only imported by tests",
+			files: []string{"internal/testhelp/testhelp.go"},
+			want:  []string{"./internal/testhelp", "./pkg/util"},
+		},
+		{
+			name:  "non go file belongs to the nearest package",
+			files: []string{"pkg/util/data/schema.json"},
+			want:  []string{".", "./pkg/api", "./pkg/util"},
+		},
+		{
+			name:  "go.mod affects its module",
+			files: []string{"go.mod"},
+			want:  []string{".", "./internal/testhelp", "./pkg/api", "./pkg/util"},
+		},
+		{
+			name:  "nested module",
+			files: []string{"tools/linter/rules/rules.go"},
+			want:  []string{"./tools/linter", "./tools/linter/rules"},
+		},
+		{
+			name:  "nested go.sum only affects the nested module",
+			files: []string{"tools/linter/go.sum"},
+			want:  []string{"./tools/linter", "./tools/linter/rules"},
+		},
+		{
+			name:  "go.work affects every module",
+			files: []string{"go.work"},
+			want:  []string{".", "./internal/testhelp", "./pkg/api", "./pkg/util", "./tools/linter", "./tools/linter/rules"},
+		},
+		{
+			name:  "no go files changed",
+			files: []string{},
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dirs := []string{}
+			for _, p := range graph.affected(tt.files) {
+				dirs = append(dirs, p.Dir)
+			}
+			if !slices.Equal(dirs, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, dirs)
+			}
+		})
+	}
+}
+
+func TestAffectedPackagesDetails(t *testing.T) {
+	packages, err := AffectedPackages("testdata/graph", []string{"pkg/util/util.go", "pkg/util/util_test.go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	byDir := map[string]AffectedPackage{}
+	for _, p := range packages {
+		byDir[p.Dir] = p
+	}
+
+	util := byDir["./pkg/util"]
+	if util.ImportPath != "example.com/root/pkg/util" || !slices.Equal(util.Files, []string{"pkg/util/util.go", "pkg/util/util_test.go"}) || util.Via != "" {
+		t.Errorf("unexpected changed package %+v", util)
+	}
+	api := byDir["./pkg/api"]
+	if api.ImportPath != "example.com/root/pkg/api" || len(api.Files) != 0 || api.Via != "example.com/root/pkg/util" {
+		t.Errorf("unexpected dependent package %+v", api)
+	}
+	if root := byDir["."]; root.ImportPath != "example.com/root" || root.Via != "example.com/root/pkg/api" {
+		t.Errorf("unexpected root package %+v", root)
+	}
+}
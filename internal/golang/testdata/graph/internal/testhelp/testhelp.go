@@ -0,0 +1,6 @@
+package testhelp
+
+import "testing"
+
+// Capture runs f as part of a test.
+func Capture(t *testing.T, f func()) { f() }
@@ -0,0 +1,5 @@
+package main
+
+import "example.com/root/pkg/api"
+
+func main() { api.Serve() }
@@ -0,0 +1,6 @@
+package api
+
+import "example.com/root/pkg/util"
+
+// Serve serves the API.
+func Serve() { util.Log("serving") }
@@ -0,0 +1,5 @@
+package api
+
+import "testing"
+
+func TestServe(t *testing.T) { Serve() }
@@ -0,0 +1,6 @@
+package util
+
+import "fmt"
+
+// Log prints a message.
+func Log(message string) { fmt.Println(message) }
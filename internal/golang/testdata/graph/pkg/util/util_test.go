@@ -0,0 +1,9 @@
+package util
+
+import (
+	"testing"
+
+	"example.com/root/internal/testhelp"
+)
+
+func TestLog(t *testing.T) { testhelp.Capture(t, func() { Log("x") }) }
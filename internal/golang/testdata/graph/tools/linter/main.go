@@ -0,0 +1,5 @@
+package main
+
+import "example.com/linter/rules"
+
+func main() { rules.Run() }
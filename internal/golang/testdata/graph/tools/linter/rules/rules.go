@@ -0,0 +1,4 @@
+package rules
+
+// Run runs the rules.
+func Run() {}
@@ -0,0 +1,45 @@
+package semver
+
+import (
+	"context"
+	"fmt"
+)
+
+// BumpOptions holds options for the semver bump command.
+type BumpOptions struct {
+	PreRelease string `flag:"--prerelease,Create a pre-release with this label (eg rc, beta, alpha)"`
+	Strict     bool   `flag:"--strict,Only accept SemVer 2.0 versions with an optional v prefix"`
+}
+
+// executeBump increments each version or tag by the bump level and prints the result,
+// keeping any tag prefix and suffix.
+func executeBump(ctx context.Context, option *BumpOptions, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: semver bump <major|minor|patch> [<version>...]")
+	}
+	level := args[0]
+	inputs, err := readInputs(args[1:])
+	if err != nil {
+		return err
+	}
+	tags, err := parseTags(inputs, option.Strict)
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("no versions specified")
+	}
+
+	for _, tv := range tags {
+		if option.PreRelease != "" {
+			tv.Version, err = tv.Version.IncrementPreRelease(level, option.PreRelease)
+		} else {
+			tv.Version, err = tv.Version.Increment(level)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to bump %s: %w", tv.Tag, err)
+		}
+		fmt.Println(tv.String())
+	}
+	return nil
+}
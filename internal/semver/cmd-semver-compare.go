@@ -0,0 +1,64 @@
+package semver
+
+import (
+	"context"
+	"fmt"
+)
+
+// CompareOptions holds options for the semver compare command.
+type CompareOptions struct {
+	Strict bool `flag:"--strict,Only accept SemVer 2.0 versions with an optional v prefix"`
+}
+
+// executeCompare compares two versions. With two args it prints -1, 0 or 1. With three args
+// (a, an operator and b) it prints nothing and returns an error if the comparison is false,
+// so it can be used directly in a shell if statement.
+func executeCompare(ctx context.Context, option *CompareOptions, args []string) error {
+	if len(args) != 2 && len(args) != 3 {
+		return fmt.Errorf("usage: semver compare <a> [<op>] <b>")
+	}
+	a, err := parseTag(args[0], option.Strict)
+	if err != nil {
+		return err
+	}
+	b, err := parseTag(args[len(args)-1], option.Strict)
+	if err != nil {
+		return err
+	}
+	r := a.Version.Compare(b.Version)
+
+	// Without an operator print the sign of the comparison.
+	if len(args) == 2 {
+		switch {
+		case r < 0:
+			fmt.Println(-1)
+		case r > 0:
+			fmt.Println(1)
+		default:
+			fmt.Println(0)
+		}
+		return nil
+	}
+
+	var ok bool
+	switch op := args[1]; op {
+	case "=", "==", "eq":
+		ok = r == 0
+	case "!=", "ne":
+		ok = r != 0
+	case "<", "lt":
+		ok = r < 0
+	case "<=", "le":
+		ok = r <= 0
+	case ">", "gt":
+		ok = r > 0
+	case ">=", "ge":
+		ok = r >= 0
+	default:
+		return fmt.Errorf("unknown comparison operator: %q", op)
+	}
+	if !ok {
+		return fmt.Errorf("%s %s %s is false", a.Version, args[1], b.Version)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package semver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// ParseOptions holds options for the semver parse command.
+type ParseOptions struct {
+	Format string `flag:"--format,Output format (text or json)"`
+	Strict bool   `flag:"--strict,Only accept SemVer 2.0 versions with an optional v prefix"`
+}
+
+// parsedVersion is the JSON representation of a parsed tag.
+type parsedVersion struct {
+	Tag        string `json:"tag"`
+	Prefix     string `json:"prefix"`
+	Version    string `json:"version"`
+	Major      int    `json:"major"`
+	Minor      int    `json:"minor"`
+	Patch      int    `json:"patch"`
+	PreRelease string `json:"prerelease,omitempty"`
+	Build      string `json:"build,omitempty"`
+	Suffix     string `json:"suffix,omitempty"`
+}
+
+// executeParse parses each version or tag and prints its normalised version (text) or its components (json).
+// It returns an error if any input is not a valid version.
+func executeParse(ctx context.Context, option *ParseOptions, args []string) error {
+	inputs, err := readInputs(args)
+	if err != nil {
+		return err
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("no versions specified")
+	}
+
+	results := []parsedVersion{}
+	invalid := 0
+	for _, input := range inputs {
+		tv, err := parseTag(input, option.Strict)
+		if err != nil {
+			slog.WarnContext(ctx, "Invalid version", "input", input, "error", err)
+			invalid++
+			continue
+		}
+		results = append(results, parsedVersion{
+			Tag:        tv.Tag,
+			Prefix:     tv.Prefix,
+			Version:    tv.Version.String(),
+			Major:      tv.Version.Major,
+			Minor:      tv.Version.Minor,
+			Patch:      tv.Version.Patch,
+			PreRelease: tv.Version.PreRelease,
+			Build:      tv.Version.Build,
+			Suffix:     tv.Suffix,
+		})
+	}
+
+	// Print the results in the requested format.
+	switch option.Format {
+	case "text":
+		for _, result := range results {
+			fmt.Println(result.Version)
+		}
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return fmt.Errorf("failed to encode json: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported --format: %q . Please use 'text' or 'json'", option.Format)
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d input(s) are not valid versions", invalid, len(inputs))
+	}
+	return nil
+}
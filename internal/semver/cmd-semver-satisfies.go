@@ -0,0 +1,55 @@
+package semver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidjspooner/ci-utility/pkg/semantic"
+)
+
+// SatisfiesOptions holds options for the semver satisfies command.
+type SatisfiesOptions struct {
+	Filter bool `flag:"--filter,Print the inputs which satisfy the constraint and only fail if none do"`
+	Strict bool `flag:"--strict,Only accept SemVer 2.0 versions with an optional v prefix"`
+}
+
+// executeSatisfies checks versions or tags against a constraint.
+// It returns an error if any input does not satisfy the constraint, or with --filter if none do.
+func executeSatisfies(ctx context.Context, option *SatisfiesOptions, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: semver satisfies <constraint> [<version>...]")
+	}
+	constraint, err := semantic.ParseConstraint(args[0])
+	if err != nil {
+		return err
+	}
+	inputs, err := readInputs(args[1:])
+	if err != nil {
+		return err
+	}
+	tags, err := parseTags(inputs, option.Strict)
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("no versions specified")
+	}
+
+	matched := 0
+	for _, tv := range tags {
+		if !constraint.Check(tv.Version) {
+			if !option.Filter {
+				return fmt.Errorf("%s does not satisfy %q", tv.Tag, constraint)
+			}
+			continue
+		}
+		matched++
+		if option.Filter {
+			fmt.Println(tv.Tag)
+		}
+	}
+	if matched == 0 {
+		return fmt.Errorf("no versions satisfy %q", constraint)
+	}
+	return nil
+}
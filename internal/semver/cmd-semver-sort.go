@@ -0,0 +1,53 @@
+package semver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// SortOptions holds options for the semver sort command.
+type SortOptions struct {
+	Reverse       bool `flag:"--reverse|-r,Sort from highest to lowest"`
+	IgnoreInvalid bool `flag:"--ignore-invalid,Skip inputs which are not valid versions instead of failing"`
+	Strict        bool `flag:"--strict,Only accept SemVer 2.0 versions with an optional v prefix"`
+}
+
+// executeSort prints the versions or tags sorted by semantic version precedence.
+func executeSort(ctx context.Context, option *SortOptions, args []string) error {
+	inputs, err := readInputs(args)
+	if err != nil {
+		return err
+	}
+
+	tags := make([]taggedVersion, 0, len(inputs))
+	for _, input := range inputs {
+		tv, err := parseTag(input, option.Strict)
+		if err != nil {
+			if option.IgnoreInvalid {
+				slog.DebugContext(ctx, "Skipping invalid version", "input", input, "error", err)
+				continue
+			}
+			return err
+		}
+		tags = append(tags, tv)
+	}
+
+	// Sort by precedence, falling back to the tag text so the output is stable.
+	slices.SortStableFunc(tags, func(a, b taggedVersion) int {
+		r := a.Version.Compare(b.Version)
+		if r == 0 {
+			r = strings.Compare(a.Tag, b.Tag)
+		}
+		if option.Reverse {
+			return -r
+		}
+		return r
+	})
+	for _, tv := range tags {
+		fmt.Println(tv.Tag)
+	}
+	return nil
+}
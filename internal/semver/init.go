@@ -0,0 +1,52 @@
+package semver
+
+import (
+	"github.com/davidjspooner/go-text-cli/pkg/cmd"
+)
+
+// Commands returns the list of semantic version CLI commands for the application.
+func AddCommandsTo(parent cmd.Command) error {
+	group := cmd.NewCommandGroup(
+		"semver",
+		"Semantic version commands (versions or tags are read from args or stdin)",
+	)
+
+	// Define the subcommands for working with versions.
+	parseCmd := cmd.NewCommand(
+		"parse",
+		"Parse versions or tags, exiting non-zero if any are invalid",
+		executeParse,
+		&ParseOptions{
+			Format: "text",
+		},
+	)
+	compareCmd := cmd.NewCommand(
+		"compare",
+		"Compare two versions, printing -1, 0 or 1, or test them with an operator (eg compare 1.2.0 '<' 1.3.0)",
+		executeCompare,
+		&CompareOptions{},
+	)
+	satisfiesCmd := cmd.NewCommand(
+		"satisfies",
+		"Check versions against a constraint (eg '>=1.4 <2', '^1.4', '~1.2 || >=3'), exiting non-zero if any do not satisfy it",
+		executeSatisfies,
+		&SatisfiesOptions{},
+	)
+	bumpCmd := cmd.NewCommand(
+		"bump",
+		"Increment versions by a bump level (major, minor or patch)",
+		executeBump,
+		&BumpOptions{},
+	)
+	sortCmd := cmd.NewCommand(
+		"sort",
+		"Sort versions or tags by semantic version precedence",
+		executeSort,
+		&SortOptions{},
+	)
+
+	// Add subcommands to the semver command.
+	group.SubCommands().MustAdd(parseCmd, compareCmd, satisfiesCmd, bumpCmd, sortCmd)
+	parent.SubCommands().MustAdd(group)
+	return nil
+}
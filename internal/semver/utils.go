@@ -0,0 +1,77 @@
+package semver
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/davidjspooner/ci-utility/pkg/semantic"
+)
+
+// taggedVersion is a version together with the tag it was extracted from.
+type taggedVersion struct {
+	Tag     string
+	Prefix  string
+	Suffix  string
+	Version semantic.Version
+}
+
+// String returns the tag with the version replaced by the current version.
+func (tv taggedVersion) String() string {
+	return tv.Prefix + tv.Version.String() + tv.Suffix
+}
+
+// readInputs returns the versions or tags given as args.
+// If there are no args, or the only arg is "-", they are read one per line from stdin.
+func readInputs(args []string) ([]string, error) {
+	if len(args) > 0 && !(len(args) == 1 && args[0] == "-") {
+		return args, nil
+	}
+	inputs := []string{}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			inputs = append(inputs, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading stdin: %w", err)
+	}
+	return inputs, nil
+}
+
+// parseTag extracts the version from a tag. If strict is set the tag must be a
+// SemVer 2.0 version with an optional "v" prefix and nothing else.
+func parseTag(tag string, strict bool) (taggedVersion, error) {
+	if strict {
+		v, err := semantic.ParseVersion(tag)
+		if err != nil {
+			return taggedVersion{}, err
+		}
+		prefix := ""
+		if strings.HasPrefix(tag, "v") {
+			prefix = "v"
+		}
+		return taggedVersion{Tag: tag, Prefix: prefix, Version: v}, nil
+	}
+	prefix, suffix, v, err := semantic.ExtractVersionFromTag(tag)
+	if err != nil {
+		return taggedVersion{}, err
+	}
+	return taggedVersion{Tag: tag, Prefix: prefix, Suffix: suffix, Version: v}, nil
+}
+
+// parseTags extracts the versions from a list of tags.
+func parseTags(tags []string, strict bool) ([]taggedVersion, error) {
+	parsed := make([]taggedVersion, 0, len(tags))
+	for _, tag := range tags {
+		tv, err := parseTag(tag, strict)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, tv)
+	}
+	return parsed, nil
+}
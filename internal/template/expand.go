@@ -0,0 +1,21 @@
+package template
+
+import (
+	"strings"
+)
+
+// NewValues returns Values holding the given map of NAME: value strings.
+func NewValues(values map[string]string) *Values {
+	return &Values{Values: values}
+}
+
+// ExpandString expands template content of the given type (go/text, go/html or markdown) and
+// returns the result. Go templates are executed with the values as their data, eg {{ .TAG }},
+// and markdown markers are replaced with values, falling back to environment variables.
+func ExpandString(content, templateType string, values *Values) (string, error) {
+	sb := strings.Builder{}
+	if err := expandTemplateStream(strings.NewReader(content), &sb, templateType, values); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -9,7 +9,8 @@ import (
 )
 
 // expandGoTextTemplate parses and executes a Go text/template with the provided content
-// and writes the result to the given io.Writer. It supports templateFunctions.
+// and writes the result to the given io.Writer. It supports templateFunctions, and the values
+// are the data of the template.
 func expandGoTextTemplate(content string, w io.Writer, values *Values) error {
 	// Parse the template content with the provided functions.
 	tmpl, err := textTemplate.New("template").Funcs(values.Functions()).Parse(content)
@@ -17,7 +18,7 @@ func expandGoTextTemplate(content string, w io.Writer, values *Values) error {
 		return fmt.Errorf("failed to parse text template: %w", err)
 	}
 	// Execute the template and write the result to the writer.
-	err = tmpl.Execute(w, nil)
+	err = tmpl.Execute(w, values.data())
 	if err != nil {
 		return fmt.Errorf("failed to expand text template: %w", err)
 	}
@@ -25,7 +26,8 @@ func expandGoTextTemplate(content string, w io.Writer, values *Values) error {
 }
 
 // expandGoHTMLTemplate parses and executes a Go html/template with the provided content
-// and writes the result to the given io.Writer. It supports templateFunctions.
+// and writes the result to the given io.Writer. It supports templateFunctions, and the values
+// are the data of the template.
 func expandGoHTMLTemplate(content string, w io.Writer, values *Values) error {
 	// Parse the HTML template content with the provided functions.
 	tmpl, err := htmlTemplate.New("template").Funcs(values.Functions()).Parse(content)
@@ -33,7 +35,7 @@ func expandGoHTMLTemplate(content string, w io.Writer, values *Values) error {
 		return fmt.Errorf("failed to parse HTML template: %w", err)
 	}
 	// Execute the template and write the result to the writer.
-	err = tmpl.Execute(w, nil)
+	err = tmpl.Execute(w, values.data())
 	if err != nil {
 		return fmt.Errorf("failed to expand HTML template: %w", err)
 	}
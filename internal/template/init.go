@@ -158,15 +158,17 @@ func expandTemplateStream(source io.Reader, target io.Writer, templateType strin
 			return err
 		}
 	case "markdown":
-		// Expand using the MarkdownExpander, which looks up environment variables.
-		expander := MarkdownExpander{
-			Lookup: func(key string) (string, error) {
-				v := os.Getenv(key)
-				if v == "" {
-					return "", fmt.Errorf("environment variable %s not set", key)
-				}
+		// Expand using the MarkdownExpander, which looks up values and then environment variables.
+		expander := NewMarkdownExpander()
+		expander.Lookup = func(key string) (string, error) {
+			if v, ok := values.data()[key]; ok {
 				return v, nil
-			},
+			}
+			v := os.Getenv(key)
+			if v == "" {
+				return "", fmt.Errorf("environment variable %s not set", key)
+			}
+			return v, nil
 		}
 		err = expander.Expand(content, target)
 		if err != nil {
@@ -36,6 +36,14 @@ func (v *Values) Get(key string) (string, error) {
 	return "", fmt.Errorf("key %s not found in values", key)
 }
 
+// data returns the values as the data of a Go template, or nil if there are none.
+func (v *Values) data() map[string]string {
+	if v == nil {
+		return nil
+	}
+	return v.Values
+}
+
 // Functions returns a map of template functions available for use in templates.
 func (v *Values) Functions() map[string]any {
 	templateFunctions := map[string]any{
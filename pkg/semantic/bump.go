@@ -1,46 +1,170 @@
 package semantic
 
 import (
-	"strings"
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
 )
 
-// Bump represents a semantic version bump level and its associated commit message hints.
+// DefaultConfigFile is the repository level config file, at the root of the repository, which may
+// contain bump and lint rules.
+const DefaultConfigFile = ".ci-utility.yaml"
+
+// BumpNone is the bump level for commits which should not cause a release.
+const BumpNone = "none"
+
+// BumpDefault is the bump level for commits which do not match any rule, including commits which
+// do not follow the Conventional Commits specification.
+const BumpDefault = "patch"
+
+// bumpRanks orders the bump levels from lowest to highest.
+var bumpRanks = map[string]int{
+	BumpNone: 0,
+	"patch":  1,
+	"minor":  2,
+	"major":  3,
+}
+
+// Bump is a rule mapping conventional commit types, or a regular expression matched
+// against the commit header, to a semantic version bump level ("major", "minor", "patch" or "none").
+// A rule with neither types nor a pattern matches every conventional commit.
 type Bump struct {
-	Level string
-	Hints []string
+	Level   string   `yaml:"level"`
+	Types   []string `yaml:"types,omitempty"`
+	Pattern string   `yaml:"pattern,omitempty"`
+
+	pattern *regexp.Regexp
 }
 
-// BumpArray is a slice of Bump objects.
+// BumpArray is a slice of Bump rules. The first matching rule determines the level of a commit.
 type BumpArray []Bump
 
-// Bumps defines the available semantic version bump levels and their hints.
+// Bumps defines the built-in bump rules.
+// Commits marked as breaking (with "!" or a BREAKING CHANGE footer) always cause a major bump.
 var Bumps = BumpArray{
-	{Level: "major", Hints: []string{"BREAKING CHANGE", "breaking:"}},
-	{Level: "minor", Hints: []string{"feat:"}},
-	{Level: "patch", Hints: []string{"fix:", "chore:", "docs:", "style:", "refactor:", "perf:", "test:"}},
+	{Level: "major", Types: []string{"breaking"}},
+	{Level: "minor", Types: []string{"feat"}},
+	{Level: "patch", Types: []string{"fix", "chore", "docs", "style", "refactor", "perf", "test"}},
 }
 
-// GetVersionBump determines the version bump level based on commit messages.
-// It returns the highest-priority bump found, or "patch" if none match.
-func (bumps BumpArray) GetVersionBump(commits []string) (string, string, error) {
-	found := map[string]string{}
-	for _, msg := range commits {
-		for _, bump := range bumps {
-			for _, hint := range bump.Hints {
-				if strings.Contains(msg, hint) {
-					found[bump.Level] = msg
-				}
+// compile validates the rule and compiles its pattern.
+func (b *Bump) compile() error {
+	if _, ok := bumpRanks[b.Level]; !ok {
+		return fmt.Errorf("unknown bump level %q (expected major, minor, patch or none)", b.Level)
+	}
+	if b.Pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(b.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid bump pattern %q: %w", b.Pattern, err)
+	}
+	b.pattern = re
+	return nil
+}
+
+// matches returns true if the rule applies to the commit.
+func (b Bump) matches(commit Commit) bool {
+	if b.Pattern != "" {
+		re := b.pattern
+		if re == nil {
+			var err error
+			if re, err = regexp.Compile(b.Pattern); err != nil {
+				return false
 			}
 		}
+		return re.MatchString(commit.Header)
+	}
+	if !commit.IsConventional() {
+		return false
 	}
+	if len(b.Types) == 0 {
+		return true
+	}
+	for _, t := range b.Types {
+		if t == commit.Type {
+			return true
+		}
+	}
+	return false
+}
 
-	// Check for bump levels in priority order.
+// LoadBumps loads bump rules from the "bumps" section of a YAML config file, eg:
+//
+//	bumps:
+//	  - level: patch
+//	    types: [security, deps]
+//	  - level: none
+//	    types: [chore, test]
+//	  - level: patch
+//	    pattern: "^Revert "
+//
+// The loaded rules are checked before the built-in Bumps. If path is empty the built-in Bumps are
+// returned.
+func LoadBumps(path string) (BumpArray, error) {
+	if path == "" {
+		return Bumps, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bump rules: %w", err)
+	}
+	var config struct {
+		Bumps BumpArray `yaml:"bumps"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse bump rules in %s: %w", path, err)
+	}
+	for i := range config.Bumps {
+		if err := config.Bumps[i].compile(); err != nil {
+			return nil, fmt.Errorf("bump rule #%d in %s: %w", i+1, path, err)
+		}
+	}
+	return append(config.Bumps, Bumps...), nil
+}
+
+// LevelFor returns the bump level for a single commit: the level of the first rule it matches, or
+// BumpDefault if it does not match any rule.
+func (bumps BumpArray) LevelFor(commit Commit) string {
+	if commit.IsConventional() && commit.Breaking {
+		return "major"
+	}
 	for _, bump := range bumps {
-		if found[bump.Level] != "" {
-			return bump.Level, found[bump.Level], nil
+		if bump.matches(commit) {
+			return bump.Level
 		}
 	}
+	return BumpDefault
+}
+
+// GetVersionBump determines the version bump level based on commit messages.
+// It returns the highest bump found, and the header of the commit which triggered it.
+func (bumps BumpArray) GetVersionBump(commits []string) (string, string, error) {
+	return bumps.GetCommitsBump(ParseCommits(commits))
+}
 
-	// Default to patch if no hints are found.
-	return "patch", "", nil
+// GetCommitsBump determines the version bump level based on parsed commits.
+// It returns the highest bump found and the header of the commit which triggered it (see HighestBump).
+func (bumps BumpArray) GetCommitsBump(commits []Commit) (string, string, error) {
+	level, commit := bumps.HighestBump(commits)
+	return level, commit.Header, nil
+}
+
+// HighestBump returns the highest bump level of the commits, as given by LevelFor, and the commit
+// which triggered it. Commits which do not match any rule, such as commits which do not follow the
+// Conventional Commits specification, are a BumpDefault bump. Without commits it returns BumpNone
+// and an empty commit.
+func (bumps BumpArray) HighestBump(commits []Commit) (string, Commit) {
+	best := BumpNone
+	var reason Commit
+	for i, commit := range commits {
+		level := bumps.LevelFor(commit)
+		if i == 0 || bumpRanks[level] > bumpRanks[best] {
+			best = level
+			reason = commit
+		}
+	}
+	return best, reason
 }
@@ -0,0 +1,67 @@
+package semantic
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHighestBump(t *testing.T) {
+	bumps := append(BumpArray{{Level: BumpNone, Types: []string{"chore", "test"}}}, Bumps...)
+	tests := []struct {
+		name     string
+		messages []string
+		want     string
+		reason   string
+	}{
+		{name: "no commits", messages: nil, want: BumpNone},
+		{name: "feat", messages: []string{"fix: crash", "feat: endpoint"}, want: "minor", reason: "feat: endpoint"},
+		{name: "breaking", messages: []string{"feat: endpoint", "fix!: api"}, want: "major", reason: "fix!: api"},
+		{name: "only none", messages: []string{"chore: deps", "test: more"}, want: BumpNone, reason: "chore: deps"},
+		{name: "non conventional", messages: []string{"update things"}, want: BumpDefault, reason: "update things"},
+		{name: "non conventional outranks none", messages: []string{"chore: deps", "update things"}, want: BumpDefault, reason: "update things"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits := ParseCommits(tt.messages)
+			got, reason := bumps.HighestBump(commits)
+			if got != tt.want || reason.Header != tt.reason {
+				t.Errorf("expected %s from %q, got %s from %q", tt.want, tt.reason, got, reason.Header)
+			}
+			for _, commit := range commits {
+				if level := bumps.LevelFor(commit); bumpRanks[level] > bumpRanks[got] {
+					t.Errorf("commit %q is a %s bump, higher than %s", commit.Header, level, got)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadBumps(t *testing.T) {
+	bumps, err := LoadBumps("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bumps) != len(Bumps) {
+		t.Errorf("expected the built-in rules without a path, got %v", bumps)
+	}
+
+	path := filepath.Join(t.TempDir(), DefaultConfigFile)
+	config := "bumps:\n  - level: none\n    types: [chore]\n"
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if bumps, err = LoadBumps(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if level := bumps.LevelFor(ParseCommit("chore: deps")); level != BumpNone {
+		t.Errorf("expected the loaded rules to make chore a none bump, got %s", level)
+	}
+	if level := bumps.LevelFor(ParseCommit("feat: api")); level != "minor" {
+		t.Errorf("expected the built-in rules after the loaded rules, got %s", level)
+	}
+
+	if _, err := LoadBumps(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
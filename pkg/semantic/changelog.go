@@ -0,0 +1,148 @@
+package semantic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChangelogSection is a titled group of commits in a changelog.
+type ChangelogSection struct {
+	Title   string   `json:"title"`
+	Commits []Commit `json:"commits"`
+}
+
+// Changelog is a list of commits grouped into sections by their conventional commit type.
+type Changelog struct {
+	From     string             `json:"from,omitempty"`
+	To       string             `json:"to,omitempty"`
+	Sections []ChangelogSection `json:"sections"`
+}
+
+// changelogTitles maps conventional commit types to section titles, in the order they are rendered.
+var changelogTitles = []struct {
+	Type  string
+	Title string
+}{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance Improvements"},
+	{"revert", "Reverts"},
+	{"docs", "Documentation"},
+	{"refactor", "Code Refactoring"},
+	{"style", "Styles"},
+	{"test", "Tests"},
+	{"build", "Build System"},
+	{"ci", "Continuous Integration"},
+	{"chore", "Chores"},
+}
+
+const (
+	breakingChangesTitle = "Breaking Changes"
+	otherChangesTitle    = "Other Changes"
+)
+
+// NewChangelog groups commits into changelog sections. Breaking changes are listed first,
+// followed by the known conventional commit types and then any other types. Commits which
+// do not follow the conventional commit format are only included if includeOther is set.
+func NewChangelog(commits []Commit, includeOther bool) Changelog {
+	groups := map[string][]Commit{}
+	otherTypes := []string{}
+	for _, commit := range commits {
+		key := commit.Type
+		switch {
+		case commit.Breaking:
+			key = breakingChangesTitle
+		case !commit.IsConventional():
+			if !includeOther {
+				continue
+			}
+			key = otherChangesTitle
+		case changelogTitle(commit.Type) == "":
+			if _, seen := groups[key]; !seen {
+				otherTypes = append(otherTypes, key)
+			}
+		}
+		groups[key] = append(groups[key], commit)
+	}
+
+	// Build the sections in rendering order.
+	changelog := Changelog{}
+	add := func(key, title string) {
+		if len(groups[key]) > 0 {
+			changelog.Sections = append(changelog.Sections, ChangelogSection{Title: title, Commits: groups[key]})
+		}
+	}
+	add(breakingChangesTitle, breakingChangesTitle)
+	for _, t := range changelogTitles {
+		add(t.Type, t.Title)
+	}
+	for _, t := range otherTypes {
+		add(t, strings.ToUpper(t[:1])+t[1:])
+	}
+	add(otherChangesTitle, otherChangesTitle)
+	return changelog
+}
+
+// changelogTitle returns the section title for a known commit type, or "" if the type is not known.
+func changelogTitle(commitType string) string {
+	for _, t := range changelogTitles {
+		if t.Type == commitType {
+			return t.Title
+		}
+	}
+	return ""
+}
+
+// IsEmpty returns true if the changelog has no commits.
+func (c Changelog) IsEmpty() bool {
+	return len(c.Sections) == 0
+}
+
+// Markdown renders the changelog as Markdown with a level 2 heading per section.
+func (c Changelog) Markdown() string {
+	sb := strings.Builder{}
+	for i, section := range c.Sections {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "## %s\n\n", section.Title)
+		for _, commit := range section.Commits {
+			sb.WriteString("- ")
+			if commit.Scope != "" {
+				fmt.Fprintf(&sb, "**%s:** ", commit.Scope)
+			}
+			sb.WriteString(commit.Summary())
+			if commit.Hash != "" {
+				fmt.Fprintf(&sb, " (%s)", commit.ShortHash())
+			}
+			sb.WriteString("\n")
+			if note := commit.BreakingNote(); note != "" {
+				fmt.Fprintf(&sb, "  %s\n", strings.ReplaceAll(note, "\n", "\n  "))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// Text renders the changelog as plain text.
+func (c Changelog) Text() string {
+	sb := strings.Builder{}
+	for i, section := range c.Sections {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "%s:\n", section.Title)
+		for _, commit := range section.Commits {
+			sb.WriteString("  - ")
+			if commit.Scope != "" {
+				fmt.Fprintf(&sb, "%s: ", commit.Scope)
+			}
+			sb.WriteString(commit.Summary())
+			if commit.Hash != "" {
+				fmt.Fprintf(&sb, " (%s)", commit.ShortHash())
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,269 @@
+package semantic
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// comparator is a single version comparison such as ">=1.4.0".
+type comparator struct {
+	op      string // one of =, !=, >, >=, <, <=
+	version Version
+}
+
+// check returns true if v satisfies the comparator.
+func (c comparator) check(v Version) bool {
+	r := v.Compare(c.version)
+	switch c.op {
+	case "=":
+		return r == 0
+	case "!=":
+		return r != 0
+	case ">":
+		return r > 0
+	case ">=":
+		return r >= 0
+	case "<":
+		return r < 0
+	case "<=":
+		return r <= 0
+	}
+	return false
+}
+
+// Constraint is a set of version ranges such as ">=1.4, <2 || ^3.1".
+// Alternatives are separated by "||" and each alternative is a list of comparators
+// separated by commas or spaces, all of which must be satisfied. Supported comparators are
+// =, !=, >, >=, <, <=, ^ (compatible with), ~ (approximately), x/* wildcards and hyphen
+// ranges such as "1.2 - 1.4". Versions may be partial (eg "1.4") and prefixed with "v".
+type Constraint struct {
+	raw          string
+	alternatives [][]comparator
+}
+
+var (
+	// partialVersionFmt matches a possibly partial version with optional wildcards, eg "v1", "1.4.x" or "1.2.3-rc.1".
+	partialVersionFmt = regexp.MustCompile(`^[vV]?(\d+|[xX*])(?:\.(\d+|[xX*]))?(?:\.(\d+|[xX*]))?(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*)?$`)
+	// constraintOpFmt splits a comparator into its operator and version.
+	constraintOpFmt = regexp.MustCompile(`^(==|=|!=|>=|<=|>|<|\^|~>|~)?(.*)$`)
+)
+
+// partialVersion is a version where only the first parts components were specified.
+type partialVersion struct {
+	version Version
+	parts   int
+}
+
+// parsePartialVersion parses a version which may have missing or wildcard components.
+func parsePartialVersion(s string) (partialVersion, error) {
+	matches := partialVersionFmt.FindStringSubmatch(s)
+	if matches == nil {
+		return partialVersion{}, fmt.Errorf("invalid version in constraint: %q", s)
+	}
+	p := partialVersion{}
+	numbers := []*int{&p.version.Major, &p.version.Minor, &p.version.Patch}
+	for i, part := range matches[1:4] {
+		if part == "" || part == "x" || part == "X" || part == "*" {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return partialVersion{}, fmt.Errorf("invalid version in constraint: %q", s)
+		}
+		*numbers[i] = n
+		p.parts++
+	}
+	if matches[4] != "" {
+		if p.parts != 3 {
+			return partialVersion{}, fmt.Errorf("pre-release requires a full version in constraint: %q", s)
+		}
+		p.version.PreRelease = matches[4]
+	}
+	return p, nil
+}
+
+// next returns the lowest version above every version matched by the partial version, eg 1.4 gives 1.5.0.
+func (p partialVersion) next() Version {
+	switch p.parts {
+	case 1:
+		return Version{Major: p.version.Major + 1}
+	case 2:
+		return Version{Major: p.version.Major, Minor: p.version.Minor + 1}
+	default:
+		return Version{Major: p.version.Major, Minor: p.version.Minor, Patch: p.version.Patch + 1}
+	}
+}
+
+// ParseConstraint parses a version constraint such as "^1.4", ">=1.4 <2" or "~1.2 || >=3".
+// An empty constraint or alternative is an error rather than matching every version; use "*" for that.
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: strings.TrimSpace(s)}
+	if c.raw == "" {
+		return Constraint{}, fmt.Errorf("empty version constraint")
+	}
+	for _, alternative := range strings.Split(s, "||") {
+		if strings.TrimSpace(alternative) == "" {
+			return Constraint{}, fmt.Errorf("empty alternative in version constraint %q", c.raw)
+		}
+		comparators, err := parseConstraintAlternative(alternative)
+		if err != nil {
+			return Constraint{}, err
+		}
+		c.alternatives = append(c.alternatives, comparators)
+	}
+	return c, nil
+}
+
+// MustParseConstraint is like ParseConstraint but panics if the constraint cannot be parsed.
+func MustParseConstraint(s string) Constraint {
+	c, err := ParseConstraint(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// parseConstraintAlternative parses a list of comparators which must all be satisfied.
+func parseConstraintAlternative(s string) ([]comparator, error) {
+	tokens := strings.Fields(strings.ReplaceAll(s, ",", " "))
+
+	// Join operators separated from their version by a space, eg ">= 1.4".
+	joined := []string{}
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if strings.Trim(token, "=!<>^~") == "" && token != "" && i+1 < len(tokens) {
+			token += tokens[i+1]
+			i++
+		}
+		joined = append(joined, token)
+	}
+
+	comparators := []comparator{}
+	for i := 0; i < len(joined); i++ {
+		// Handle hyphen ranges, eg "1.2 - 1.4".
+		if i+2 < len(joined) && joined[i+1] == "-" {
+			lower, err := parsePartialVersion(joined[i])
+			if err != nil {
+				return nil, err
+			}
+			upper, err := parsePartialVersion(joined[i+2])
+			if err != nil {
+				return nil, err
+			}
+			comparators = append(comparators, comparator{">=", lower.version})
+			if upper.parts == 3 {
+				comparators = append(comparators, comparator{"<=", upper.version})
+			} else if upper.parts > 0 {
+				comparators = append(comparators, comparator{"<", upper.next()})
+			}
+			i += 2
+			continue
+		}
+		expanded, err := parseComparator(joined[i])
+		if err != nil {
+			return nil, err
+		}
+		comparators = append(comparators, expanded...)
+	}
+	return comparators, nil
+}
+
+// parseComparator expands a single comparator into one or more simple comparisons.
+func parseComparator(s string) ([]comparator, error) {
+	matches := constraintOpFmt.FindStringSubmatch(s)
+	op := matches[1]
+	p, err := parsePartialVersion(matches[2])
+	if err != nil {
+		return nil, err
+	}
+	v := p.version
+
+	// A bare wildcard matches everything.
+	if p.parts == 0 {
+		if op == "" || op == "=" || op == "==" || op == ">=" || op == "^" || op == "~" || op == "~>" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("invalid constraint: %q", s)
+	}
+
+	switch op {
+	case "", "=", "==":
+		if p.parts == 3 {
+			return []comparator{{"=", v}}, nil
+		}
+		return []comparator{{">=", v}, {"<", p.next()}}, nil
+	case "!=":
+		if p.parts != 3 {
+			return nil, fmt.Errorf("!= requires a full version: %q", s)
+		}
+		return []comparator{{"!=", v}}, nil
+	case ">":
+		if p.parts == 3 {
+			return []comparator{{">", v}}, nil
+		}
+		return []comparator{{">=", p.next()}}, nil
+	case ">=":
+		return []comparator{{">=", v}}, nil
+	case "<":
+		return []comparator{{"<", v}}, nil
+	case "<=":
+		if p.parts == 3 {
+			return []comparator{{"<=", v}}, nil
+		}
+		return []comparator{{"<", p.next()}}, nil
+	case "~", "~>":
+		// ~1.2.3 allows patch changes, ~1 allows minor changes.
+		if p.parts == 1 {
+			return []comparator{{">=", v}, {"<", Version{Major: v.Major + 1}}}, nil
+		}
+		return []comparator{{">=", v}, {"<", Version{Major: v.Major, Minor: v.Minor + 1}}}, nil
+	case "^":
+		// ^ allows changes that do not modify the left-most non-zero component.
+		upper := Version{Major: v.Major + 1}
+		if v.Major == 0 && p.parts >= 2 {
+			upper = Version{Minor: v.Minor + 1}
+			if v.Minor == 0 && p.parts == 3 {
+				upper = Version{Patch: v.Patch + 1}
+			}
+		}
+		return []comparator{{">=", v}, {"<", upper}}, nil
+	}
+	return nil, fmt.Errorf("invalid constraint operator in %q", s)
+}
+
+// Check returns true if the version satisfies the constraint.
+// As with npm, a pre-release version only satisfies an alternative if one of its comparators
+// refers to a pre-release of the same major.minor.patch.
+func (c Constraint) Check(v Version) bool {
+	for _, alternative := range c.alternatives {
+		if checkAlternative(alternative, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAlternative returns true if the version satisfies every comparator.
+func checkAlternative(comparators []comparator, v Version) bool {
+	for _, c := range comparators {
+		if !c.check(v) {
+			return false
+		}
+	}
+	if !v.IsPreRelease() {
+		return true
+	}
+	for _, c := range comparators {
+		if c.version.IsPreRelease() && c.version.Release() == v.Release() {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the constraint as it was parsed.
+func (c Constraint) String() string {
+	return c.raw
+}
@@ -0,0 +1,64 @@
+package semantic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConstraintCheck(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{constraint: "^1.4", version: "1.9.3", want: true},
+		{constraint: "^1.4", version: "2.0.0", want: false},
+		{constraint: "^0.2.3", version: "0.2.9", want: true},
+		{constraint: "^0.2.3", version: "0.3.0", want: false},
+		{constraint: "~1.2", version: "1.2.7", want: true},
+		{constraint: "~1.2", version: "1.3.0", want: false},
+		{constraint: ">=1.4, <2", version: "1.4.0", want: true},
+		{constraint: ">= 1.4 < 2", version: "2.0.0", want: false},
+		{constraint: "1.2 - 1.4", version: "1.4.9", want: true},
+		{constraint: "~1.2 || >=3", version: "3.1.0", want: true},
+		{constraint: "~1.2 || >=3", version: "2.0.0", want: false},
+		{constraint: "*", version: "0.0.1", want: true},
+		{constraint: "1.x", version: "1.7.0", want: true},
+		{constraint: ">=1.4", version: "1.5.0-rc.1", want: false},
+		{constraint: ">=1.5.0-rc.1", version: "1.5.0-rc.2", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint+" "+tt.version, func(t *testing.T) {
+			c, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := c.Check(MustParseVersion(tt.version)); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	tests := []struct {
+		constraint string
+		wantErr    string
+	}{
+		{constraint: "", wantErr: "empty version constraint"},
+		{constraint: "   ", wantErr: "empty version constraint"},
+		{constraint: "||", wantErr: "empty alternative"},
+		{constraint: "^1.4 ||", wantErr: "empty alternative"},
+		{constraint: "|| ^1.4", wantErr: "empty alternative"},
+		{constraint: "!=1.4", wantErr: "!= requires a full version"},
+		{constraint: ">=banana", wantErr: "invalid version in constraint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			_, err := ParseConstraint(tt.constraint)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
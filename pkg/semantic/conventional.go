@@ -0,0 +1,154 @@
+package semantic
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Footer represents a single git trailer style footer of a conventional commit, eg "Refs: #123".
+type Footer struct {
+	Token string `json:"token"`
+	Value string `json:"value"`
+}
+
+// Commit represents a commit message parsed according to the Conventional Commits specification.
+// Messages which do not follow the specification still populate Header, Body and Message but leave Type empty.
+type Commit struct {
+	Hash        string   `json:"hash,omitempty"`
+	Message     string   `json:"message"`        // the full, unparsed commit message
+	Header      string   `json:"header"`         // the first line of the message
+	Type        string   `json:"type,omitempty"` // lower case commit type, eg "feat" or "fix"
+	Scope       string   `json:"scope,omitempty"`
+	Breaking    bool     `json:"breaking,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Body        string   `json:"body,omitempty"`
+	Footers     []Footer `json:"footers,omitempty"`
+}
+
+var (
+	// conventionalHeaderFmt matches "type(scope)!: description".
+	conventionalHeaderFmt = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*)(?:\(([^()\r\n]*)\))?(!)?: (.*\S.*)$`)
+	// footerFmt matches "Token: value" or "Token #value". Tokens use - instead of spaces except for BREAKING CHANGE.
+	footerFmt = regexp.MustCompile(`^(BREAKING CHANGE|BREAKING-CHANGE|[A-Za-z][A-Za-z0-9-]*)(?:: | #)(.*)$`)
+)
+
+// ParseCommit parses a commit message according to the Conventional Commits specification.
+func ParseCommit(message string) Commit {
+	message = strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n"))
+	c := Commit{Message: message}
+	lines := strings.Split(message, "\n")
+	c.Header = strings.TrimSpace(lines[0])
+
+	// Split the remainder into paragraphs; the body follows a blank line after the header.
+	rest := strings.TrimSpace(strings.Join(lines[1:], "\n"))
+	paragraphs := []string{}
+	if rest != "" {
+		paragraphs = strings.Split(rest, "\n\n")
+	}
+
+	// Footers, if present, are the last paragraph of the message.
+	if n := len(paragraphs); n > 0 {
+		footers, ok := parseFooters(paragraphs[n-1])
+		if ok {
+			c.Footers = footers
+			paragraphs = paragraphs[:n-1]
+		}
+	}
+	c.Body = strings.TrimSpace(strings.Join(paragraphs, "\n\n"))
+
+	matches := conventionalHeaderFmt.FindStringSubmatch(c.Header)
+	if matches == nil {
+		return c
+	}
+	c.Type = strings.ToLower(matches[1])
+	c.Scope = strings.TrimSpace(matches[2])
+	c.Breaking = matches[3] == "!"
+	c.Description = strings.TrimSpace(matches[4])
+	for _, footer := range c.Footers {
+		if footer.IsBreakingChange() {
+			c.Breaking = true
+		}
+	}
+	return c
+}
+
+// ParseCommits parses a list of commit messages according to the Conventional Commits specification.
+func ParseCommits(messages []string) []Commit {
+	commits := make([]Commit, 0, len(messages))
+	for _, msg := range messages {
+		commits = append(commits, ParseCommit(msg))
+	}
+	return commits
+}
+
+// parseFooters parses a paragraph as a list of footers.
+// It returns false unless every line is a footer or continues one, so that a body paragraph which
+// happens to start with "Word: " is kept in the body. A line continues the previous footer if it is
+// indented, as with git trailers, or follows a BREAKING CHANGE footer, whose description is often
+// several lines of prose.
+func parseFooters(paragraph string) ([]Footer, bool) {
+	var footers []Footer
+	for _, line := range strings.Split(paragraph, "\n") {
+		matches := footerFmt.FindStringSubmatch(line)
+		if matches != nil {
+			footers = append(footers, Footer{Token: matches[1], Value: strings.TrimSpace(matches[2])})
+			continue
+		}
+		if len(footers) == 0 {
+			return nil, false
+		}
+		last := &footers[len(footers)-1]
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && !last.IsBreakingChange() {
+			return nil, false
+		}
+		// Continuation of the previous footer value.
+		last.Value = strings.TrimSpace(last.Value + "\n" + strings.TrimSpace(line))
+	}
+	return footers, len(footers) > 0
+}
+
+// IsBreakingChange returns true if the footer announces a breaking change.
+func (f Footer) IsBreakingChange() bool {
+	return f.Token == "BREAKING CHANGE" || f.Token == "BREAKING-CHANGE"
+}
+
+// IsConventional returns true if the commit header follows the Conventional Commits specification.
+func (c Commit) IsConventional() bool {
+	return c.Type != ""
+}
+
+// Footer returns the value of the first footer with the given token, or "" if not present.
+func (c Commit) Footer(token string) string {
+	for _, footer := range c.Footers {
+		if strings.EqualFold(footer.Token, token) {
+			return footer.Value
+		}
+	}
+	return ""
+}
+
+// Summary returns the description of a conventional commit, or the header of any other commit.
+func (c Commit) Summary() string {
+	if c.IsConventional() {
+		return c.Description
+	}
+	return c.Header
+}
+
+// ShortHash returns the first 7 characters of the commit hash.
+func (c Commit) ShortHash() string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+	return c.Hash
+}
+
+// BreakingNote returns the text of the BREAKING CHANGE footer, or "" if there is none.
+func (c Commit) BreakingNote() string {
+	for _, footer := range c.Footers {
+		if footer.IsBreakingChange() {
+			return footer.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package semantic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCommit(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		wantType string
+		breaking bool
+		body     string
+		footers  []Footer
+	}{
+		{
+			name:     "header only",
+			message:  "feat(api): add endpoint",
+			wantType: "feat",
+		},
+		{
+			name:     "body and footers",
+			message:  "fix: crash\n\nThe cache was read before it was filled.\n\nRefs: #123\nReviewed-by: Jo Bloggs",
+			wantType: "fix",
+			body:     "The cache was read before it was filled.",
+			footers:  []Footer{{Token: "Refs", Value: "#123"}, {Token: "Reviewed-by", Value: "Jo Bloggs"}},
+		},
+		{
+			name:     "hash separator",
+			message:  "fix: crash\n\nCloses #12",
+			wantType: "fix",
+			footers:  []Footer{{Token: "Closes", Value: "12"}},
+		},
+		{
+			name:     "body paragraph starting like a footer",
+			message:  "fix: crash\n\nNote: this also fixes the start up\nwhen the cache is cold.",
+			wantType: "fix",
+			body:     "Note: this also fixes the start up\nwhen the cache is cold.",
+		},
+		{
+			name:     "indented footer continuation",
+			message:  "fix: crash\n\nRefs: #1\n  and #2",
+			wantType: "fix",
+			footers:  []Footer{{Token: "Refs", Value: "#1\nand #2"}},
+		},
+		{
+			name:     "multi-line breaking change",
+			message:  "feat: config\n\nBREAKING CHANGE: the config file\nmoved to ~/.config",
+			wantType: "feat",
+			breaking: true,
+			footers:  []Footer{{Token: "BREAKING CHANGE", Value: "the config file\nmoved to ~/.config"}},
+		},
+		{
+			name:     "breaking marker",
+			message:  "refactor!: drop the old api",
+			wantType: "refactor",
+			breaking: true,
+		},
+		{
+			name:    "not conventional",
+			message: "Update things\n\nSome details.",
+			body:    "Some details.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := ParseCommit(tt.message)
+			if c.Type != tt.wantType || c.Breaking != tt.breaking || c.Body != tt.body {
+				t.Errorf("expected type %q, breaking %v and body %q, got %q, %v and %q", tt.wantType, tt.breaking, tt.body, c.Type, c.Breaking, c.Body)
+			}
+			if !reflect.DeepEqual(c.Footers, tt.footers) {
+				t.Errorf("expected footers %v, got %v", tt.footers, c.Footers)
+			}
+		})
+	}
+}
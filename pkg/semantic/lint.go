@@ -0,0 +1,143 @@
+package semantic
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLintTypes are the commit types allowed by default, following the Angular convention.
+var DefaultLintTypes = []string{"feat", "fix", "build", "chore", "ci", "docs", "style", "refactor", "perf", "test", "revert"}
+
+// DefaultMaxHeaderLength is the default maximum length of a commit header.
+const DefaultMaxHeaderLength = 72
+
+// LintRules configures the checks applied to commit messages.
+type LintRules struct {
+	Types           []string `yaml:"types,omitempty" json:"types,omitempty"`   // allowed types, any type if empty
+	Scopes          []string `yaml:"scopes,omitempty" json:"scopes,omitempty"` // allowed scopes, any scope if empty
+	RequireScope    bool     `yaml:"require-scope,omitempty" json:"require-scope,omitempty"`
+	MaxHeaderLength int      `yaml:"max-header-length,omitempty" json:"max-header-length,omitempty"` // no limit if 0 or less
+}
+
+// LintViolation is a single problem found in a commit message.
+type LintViolation struct {
+	Hash    string `json:"hash,omitempty"`
+	Header  string `json:"header"`
+	Rule    string `json:"rule"` // eg "type-enum" or "header-max-length"
+	Message string `json:"message"`
+	Line    int    `json:"line"` // line of the commit message, starting at 1
+}
+
+// ShortHash returns the abbreviated hash of the commit with the violation.
+func (v LintViolation) ShortHash() string {
+	return Commit{Hash: v.Hash}.ShortHash()
+}
+
+// String returns the violation as "<short hash>: <message> [<rule>]".
+func (v LintViolation) String() string {
+	if v.Hash == "" {
+		return fmt.Sprintf("%s [%s]", v.Message, v.Rule)
+	}
+	return fmt.Sprintf("%s: %s [%s]", v.ShortHash(), v.Message, v.Rule)
+}
+
+// LoadLintRules loads lint rules from the "lint" section of a YAML config file, eg:
+//
+//	lint:
+//	  types: [feat, fix, chore, deps]
+//	  scopes: [api, cli]
+//	  require-scope: true
+//	  max-header-length: 100
+//
+// Unset values use the defaults, and the default types include any types named in the "bumps"
+// section so that custom bump rules are always allowed. If path is empty the defaults are returned.
+func LoadLintRules(path string) (LintRules, error) {
+	rules := LintRules{
+		Types:           append([]string{}, DefaultLintTypes...),
+		MaxHeaderLength: DefaultMaxHeaderLength,
+	}
+	if path == "" {
+		return rules, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return LintRules{}, fmt.Errorf("failed to read lint rules: %w", err)
+	}
+	var config struct {
+		Lint  LintRules `yaml:"lint"`
+		Bumps BumpArray `yaml:"bumps"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return LintRules{}, fmt.Errorf("failed to parse lint rules in %s: %w", path, err)
+	}
+	if len(config.Lint.Types) > 0 {
+		rules.Types = config.Lint.Types
+	} else {
+		for _, bump := range config.Bumps {
+			for _, t := range bump.Types {
+				if t != "breaking" && !contains(rules.Types, t) {
+					rules.Types = append(rules.Types, t)
+				}
+			}
+		}
+	}
+	rules.Scopes = config.Lint.Scopes
+	rules.RequireScope = config.Lint.RequireScope
+	if config.Lint.MaxHeaderLength != 0 {
+		rules.MaxHeaderLength = config.Lint.MaxHeaderLength
+	}
+	return rules, nil
+}
+
+// Lint checks a commit message against the rules and returns any violations.
+func (rules LintRules) Lint(commit Commit) []LintViolation {
+	violations := []LintViolation{}
+	add := func(rule string, line int, format string, args ...any) {
+		violations = append(violations, LintViolation{
+			Hash:    commit.Hash,
+			Header:  commit.Header,
+			Rule:    rule,
+			Message: fmt.Sprintf(format, args...),
+			Line:    line,
+		})
+	}
+
+	if rules.MaxHeaderLength > 0 {
+		if n := utf8.RuneCountInString(commit.Header); n > rules.MaxHeaderLength {
+			add("header-max-length", 1, "header is %d characters long, the maximum is %d", n, rules.MaxHeaderLength)
+		}
+	}
+	lines := strings.Split(commit.Message, "\n")
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		add("body-leading-blank", 2, "the body must be separated from the header by a blank line")
+	}
+
+	if !commit.IsConventional() {
+		add("header-format", 1, "header %q does not match \"type(scope): description\"", commit.Header)
+		return violations
+	}
+	if len(rules.Types) > 0 && !contains(rules.Types, commit.Type) {
+		add("type-enum", 1, "type %q is not one of %s", commit.Type, strings.Join(rules.Types, ", "))
+	}
+	switch {
+	case commit.Scope == "" && rules.RequireScope:
+		add("scope-empty", 1, "a scope is required")
+	case commit.Scope != "" && len(rules.Scopes) > 0 && !contains(rules.Scopes, commit.Scope):
+		add("scope-enum", 1, "scope %q is not one of %s", commit.Scope, strings.Join(rules.Scopes, ", "))
+	}
+	return violations
+}
+
+// contains returns true if the list contains the value.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
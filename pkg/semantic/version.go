@@ -4,40 +4,133 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
-// Version represents a semantic version (major.minor.patch).
+// Version represents a semantic version (major.minor.patch[-prerelease][+build]) as defined by SemVer 2.0.
 type Version struct {
-	Major int
-	Minor int
-	Patch int
+	Major      int
+	Minor      int
+	Patch      int
+	PreRelease string // dot separated pre-release identifiers, eg "rc.1"
+	Build      string // dot separated build metadata, eg "20250101.abc123"
 }
 
-// String returns the version as a string in the form "major.minor.patch".
+// String returns the version as a string in the form "major.minor.patch[-prerelease][+build]".
 func (v Version) String() string {
-	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.PreRelease != "" {
+		s += "-" + v.PreRelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
 }
 
 // Increment returns a new Version incremented by the specified bump type ("major", "minor", or "patch").
+// Any pre-release identifiers and build metadata are dropped from the result. If the version is a
+// pre-release the bump is applied relative to the release it leads up to, so 1.3.0-rc.1 incremented
+// by "minor" or "patch" gives 1.3.0.
 func (v Version) Increment(bump string) (Version, error) {
+	pre := v.IsPreRelease()
 	switch bump {
 	case "major":
-		return Version{v.Major + 1, 0, 0}, nil
+		if pre && v.Minor == 0 && v.Patch == 0 {
+			return v.Release(), nil
+		}
+		return Version{Major: v.Major + 1}, nil
 	case "minor":
-		return Version{v.Major, v.Minor + 1, 0}, nil
+		if pre && v.Patch == 0 {
+			return v.Release(), nil
+		}
+		return Version{Major: v.Major, Minor: v.Minor + 1}, nil
 	case "patch":
-		return Version{v.Major, v.Minor, v.Patch + 1}, nil
+		if pre {
+			return v.Release(), nil
+		}
+		return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}, nil
 	default:
 		return Version{}, fmt.Errorf("unknown version bump type: %q", bump)
 	}
 }
 
-// IsValid returns true if the version is valid (all parts are non-negative).
+// IncrementPreRelease returns the next pre-release version for the given bump type and label (eg "rc").
+// A release is bumped and labelled, so 1.2.0 with "minor" and "rc" gives 1.3.0-rc.1. A pre-release
+// with the same label has its sequence number incremented, so 1.3.0-rc.1 gives 1.3.0-rc.2, unless
+// the bump requires a higher release, in which case the sequence restarts at 1.
+func (v Version) IncrementPreRelease(bump, label string) (Version, error) {
+	if label == "" {
+		return Version{}, fmt.Errorf("pre-release label is required")
+	}
+	if !preReleaseFmt.MatchString(label) {
+		return Version{}, fmt.Errorf("invalid pre-release label: %q", label)
+	}
+	next, err := v.Increment(bump)
+	if err != nil {
+		return Version{}, err
+	}
+	if !v.IsPreRelease() || next != v.Release() {
+		next.PreRelease = label + ".1"
+		return next, nil
+	}
+
+	// Continue or switch the pre-release sequence for the same release.
+	currentLabel, sequence := v.preReleaseSequence()
+	if currentLabel == label {
+		next.PreRelease = fmt.Sprintf("%s.%d", label, sequence+1)
+	} else {
+		next.PreRelease = label + ".1"
+	}
+	if !next.IsGreaterThan(v) {
+		return Version{}, fmt.Errorf("pre-release %s would not be greater than %s", next, v)
+	}
+	return next, nil
+}
+
+// preReleaseSequence splits the pre-release into a label and a trailing sequence number,
+// eg "rc.2" gives "rc" and 2. If there is no trailing number the sequence is 0.
+func (v Version) preReleaseSequence() (string, int) {
+	i := strings.LastIndex(v.PreRelease, ".")
+	if i < 0 {
+		return v.PreRelease, 0
+	}
+	n, err := strconv.Atoi(v.PreRelease[i+1:])
+	if err != nil || !isNumericIdentifier(v.PreRelease[i+1:]) {
+		return v.PreRelease, 0
+	}
+	return v.PreRelease[:i], n
+}
+
+// Release returns the version with any pre-release identifiers and build metadata removed,
+// eg 1.3.0-rc.2+abc gives 1.3.0.
+func (v Version) Release() Version {
+	return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+}
+
+// IsValid returns true if the version is valid (all parts are non-negative and
+// the pre-release and build identifiers are well formed).
 func (v Version) IsValid() bool {
-	return v.Major >= 0 && v.Minor >= 0 && v.Patch >= 0
+	if v.Major < 0 || v.Minor < 0 || v.Patch < 0 {
+		return false
+	}
+	if v.PreRelease != "" && !preReleaseFmt.MatchString(v.PreRelease) {
+		return false
+	}
+	if v.Build != "" && !buildFmt.MatchString(v.Build) {
+		return false
+	}
+	return true
+}
+
+// IsPreRelease returns true if the version has pre-release identifiers.
+func (v Version) IsPreRelease() bool {
+	return v.PreRelease != ""
 }
 
-// Compare compares two versions. Returns positive if v > other, negative if v < other, zero if equal.
+// Compare compares two versions using SemVer 2.0 precedence.
+// Returns positive if v > other, negative if v < other, zero if equal.
+// Build metadata is ignored when determining precedence.
 func (v Version) Compare(other Version) int {
 	if v.Major != other.Major {
 		return v.Major - other.Major
@@ -45,7 +138,71 @@ func (v Version) Compare(other Version) int {
 	if v.Minor != other.Minor {
 		return v.Minor - other.Minor
 	}
-	return v.Patch - other.Patch
+	if v.Patch != other.Patch {
+		return v.Patch - other.Patch
+	}
+	return comparePreRelease(v.PreRelease, other.PreRelease)
+}
+
+// comparePreRelease compares two pre-release strings using SemVer 2.0 precedence rules.
+// A version without a pre-release has higher precedence than one with a pre-release.
+func comparePreRelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if r := compareIdentifier(aParts[i], bParts[i]); r != 0 {
+			return r
+		}
+	}
+	// A larger set of identifiers has higher precedence if all preceding ones are equal.
+	return len(aParts) - len(bParts)
+}
+
+// compareIdentifier compares a single pre-release identifier.
+// Numeric identifiers are compared numerically and always have lower precedence than alphanumeric ones.
+// They are compared by length and then lexically, as they may not fit in an int.
+func compareIdentifier(a, b string) int {
+	aNumeric := isNumericIdentifier(a)
+	bNumeric := isNumericIdentifier(b)
+	switch {
+	case aNumeric && bNumeric:
+		// Leading zeros are not valid, but ignore any in versions which were not validated.
+		a = strings.TrimLeft(a, "0")
+		b = strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			return len(a) - len(b)
+		}
+		return strings.Compare(a, b)
+	case aNumeric:
+		return -1
+	case bNumeric:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// isNumericIdentifier returns true if a pre-release identifier is made of digits only, eg "12"
+// but not "-1" or "rc1".
+func isNumericIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 // IsGreaterThan returns true if v is greater than other.
@@ -73,14 +230,14 @@ func (v Version) IsLessThanOrEqual(other Version) bool {
 	return v.Compare(other) <= 0
 }
 
-// IsZero returns true if the version is 0.0.0.
+// IsZero returns true if the version is 0.0.0 with no pre-release or build metadata.
 func (v Version) IsZero() bool {
-	return v.Major == 0 && v.Minor == 0 && v.Patch == 0
+	return v == Version{}
 }
 
-// IsEmpty returns true if the version is 0.0.0.
+// IsEmpty returns true if the version is 0.0.0 with no pre-release or build metadata.
 func (v Version) IsEmpty() bool {
-	return v.Major == 0 && v.Minor == 0 && v.Patch == 0
+	return v == Version{}
 }
 
 // IsNotEmpty returns true if the version is not 0.0.0.
@@ -88,13 +245,44 @@ func (v Version) IsNotEmpty() bool {
 	return !v.IsEmpty()
 }
 
-var versionFmt = regexp.MustCompile(`(.*)(\d+)\.(\d+)\.(\d+)(.*)`)
+var (
+	// versionFmt matches an optional prefix, major.minor.patch, optional pre-release and build metadata and any trailing suffix.
+	versionFmt    = regexp.MustCompile(`^(.*?)(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(.*)$`)
+	preReleaseFmt = regexp.MustCompile(`^(?:0|[1-9]\d*|\d*[A-Za-z-][0-9A-Za-z-]*)(?:\.(?:0|[1-9]\d*|\d*[A-Za-z-][0-9A-Za-z-]*))*$`)
+	buildFmt      = regexp.MustCompile(`^[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*$`)
+)
+
+// ParseVersion parses a strict SemVer 2.0 version string, optionally prefixed with "v".
+func ParseVersion(s string) (Version, error) {
+	prefix, suffix, v, err := ExtractVersionFromTag(s)
+	if err != nil {
+		return Version{}, err
+	}
+	if (prefix != "" && prefix != "v") || suffix != "" {
+		return Version{}, fmt.Errorf("invalid semantic version: %q", s)
+	}
+	if !v.IsValid() {
+		return Version{}, fmt.Errorf("invalid semantic version: %q", s)
+	}
+	return v, nil
+}
+
+// MustParseVersion is like ParseVersion but panics if the version cannot be parsed.
+func MustParseVersion(s string) Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
 
 // ExtractVersionFromTag extracts a semantic version from a tag string.
+// It returns the text before the version (eg "v"), any text after the version that
+// is not pre-release or build metadata, and the parsed version.
 func ExtractVersionFromTag(tag string) (string, string, Version, error) {
 	// Match the tag against the version format.
 	matches := versionFmt.FindStringSubmatch(tag)
-	if len(matches) != 6 {
+	if len(matches) != 8 {
 		return "", "", Version{}, fmt.Errorf("invalid version tag format: %s", tag)
 	}
 	v := Version{}
@@ -113,6 +301,8 @@ func ExtractVersionFromTag(tag string) (string, string, Version, error) {
 	if err != nil {
 		return "", "", v, fmt.Errorf("error converting patch version: %v", err)
 	}
+	v.PreRelease = matches[5]
+	v.Build = matches[6]
 	//return the prefix, suffix, version struct, and nil error
-	return matches[1], matches[5], v, nil
+	return matches[1], matches[7], v, nil
 }
@@ -0,0 +1,49 @@
+package semantic
+
+import "testing"
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int // sign of the comparison
+	}{
+		{a: "1.0.0", b: "1.0.0", want: 0},
+		{a: "1.10.0", b: "1.9.0", want: 1},
+		{a: "1.0.0", b: "1.0.0-rc.1", want: 1},
+		{a: "1.0.0-alpha", b: "1.0.0-alpha.1", want: -1},
+		{a: "1.0.0-alpha.1", b: "1.0.0-alpha.beta", want: -1},
+		{a: "1.0.0-beta.2", b: "1.0.0-beta.11", want: -1},
+		{a: "1.0.0-rc.1", b: "1.0.0+build.5", want: -1},
+		{a: "1.0.0+build.1", b: "1.0.0+build.2", want: 0},
+		// Identifiers with a hyphen are alphanumeric, so sort after every numeric one.
+		{a: "1.0.0-rc.-1", b: "1.0.0-rc.1", want: 1},
+		{a: "1.0.0-rc.1-1", b: "1.0.0-rc.99", want: 1},
+		// Numeric identifiers too long for an int are still compared numerically.
+		{a: "1.0.0-rc.99999999999999999999", b: "1.0.0-rc.100000000000000000000", want: -1},
+		{a: "1.0.0-rc.99999999999999999999", b: "1.0.0-rc.alpha", want: -1},
+		{a: "1.0.0-rc.99999999999999999999", b: "1.0.0-rc.2", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			a := MustParseVersion(tt.a)
+			b := MustParseVersion(tt.b)
+			if got := sign(a.Compare(b)); got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got)
+			}
+			if got := sign(b.Compare(a)); got != -tt.want {
+				t.Errorf("expected %d comparing the other way, got %d", -tt.want, got)
+			}
+		})
+	}
+}
+
+// sign returns -1, 0 or 1 for the sign of n.
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}